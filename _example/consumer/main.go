@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/codec"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
 	"github.com/GabrielHCataldo/go-logger/logger"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -43,18 +47,20 @@ func main() {
 	receiveMessage()
 	completeOptions()
 	simpleReceiveMessageAsync()
+	receiveMessageBatch()
+	receive()
 }
 
 func simpleReceiveMessage() {
-	sqs.SimpleReceiveMessage(os.Getenv("SQS_QUEUE_TEST_STRING_URL"), handlerSimple)
+	_ = sqs.SimpleReceiveMessage(context.TODO(), os.Getenv("SQS_QUEUE_TEST_STRING_URL"), handlerSimple)
 }
 
 func simpleReceiveMessageStruct() {
-	sqs.SimpleReceiveMessage(os.Getenv("SQS_QUEUE_TEST_URL"), handler)
+	_ = sqs.SimpleReceiveMessage(context.TODO(), os.Getenv("SQS_QUEUE_TEST_URL"), handler)
 }
 
 func receiveMessage() {
-	sqs.ReceiveMessage(os.Getenv("SQS_QUEUE_TEST_STRING_URL"), handlerReceiveMessage)
+	_ = sqs.ReceiveMessage(context.TODO(), os.Getenv("SQS_QUEUE_TEST_STRING_URL"), handlerReceiveMessage)
 }
 
 func completeOptions() {
@@ -78,17 +84,74 @@ func completeOptions() {
 		SetReceiveRequestAttemptId("").
 		// The duration for which the call waits for a message to arrive in
 		// the queue before returning (default: 0)
-		SetWaitTimeSeconds(1 * time.Second)
-	sqs.SimpleReceiveMessage(os.Getenv("SQS_QUEUE_TEST_URL"), handler, opt)
+		SetWaitTimeSeconds(1 * time.Second).
+		// Maximum number of messages processed at the same time, counting all pollers (default: 1)
+		SetMaxInFlightMessages(10).
+		// Number of goroutines searching for messages in the queue at the same time (default: 1)
+		SetPollers(2).
+		// Maximum time to wait for the messages in process when the consumer is stopped (default: 30 seconds)
+		SetDrainTimeout(30 * time.Second).
+		// Policy applied when fails to receive messages (default: backoff from 1 second up to 1 minute, never gives up)
+		SetReceiveErrorPolicy(option.ReceiveErrorPolicy{MaxAttempts: 10}).
+		// Function called with every error that occurs in the consumer (default: nil)
+		SetOnError(func(err error) { logger.Error("consumer error:", err) }).
+		// Extends the message visibility in the background while the handler is running (default: nil)
+		SetVisibilityHeartbeat(option.VisibilityHeartbeat{Interval: 10 * time.Second}).
+		// Url of the queue where the messages are sent when the handler returns sqs.DeadLetter (default: nil)
+		SetDeadLetterQueueUrl(os.Getenv("SQS_QUEUE_TEST_DLQ_URL")).
+		// Delay to retry the messages that failed, based on the receive count (default: nil)
+		SetRedeliveryBackoff(option.Backoff{Initial: 30 * time.Second, Max: 15 * time.Minute}).
+		// Buffer to remove the processed messages in groups with DeleteMessageBatch (default: groups of 10, flush every 1 second)
+		SetDeleteBuffer(option.DeleteBuffer{Size: 10, FlushInterval: time.Second}).
+		// codec of the body when the message has no content type attribute (default: nil, converted by type)
+		SetCodec(codec.JSON).
+		// decode the JSON body strictly, accepting zero values and returning the field path on errors (default: false)
+		SetStrictBodyDecoding(true).
+		// with strict body decoding, reject JSON bodies with unknown fields (default: false)
+		SetDisallowUnknownFields(false).
+		// function called with the messages that cannot be converted and the error (default: nil)
+		SetOnPoisonMessage(func(queueUrl string, message types.Message, err error) {
+			logger.Error("poison message:", *message.MessageId, err)
+		}).
+		// queue where the undecodable messages, or those that exceeded the max receive count, are sent (default: nil)
+		SetQuarantineQueueUrl(os.Getenv("SQS_QUEUE_TEST_QUARANTINE_URL")).
+		// maximum receive count of a message before it is sent to the quarantine queue (default: 0, disabled)
+		SetMaxReceiveCount(5).
+		// middlewares that wrap the handler of each message, after the global ones registered with sqs.Use (default: nil)
+		SetMiddlewares(sqs.RecoveryMiddleware())
+	_ = sqs.SimpleReceiveMessage(context.TODO(), os.Getenv("SQS_QUEUE_TEST_URL"), handler, opt)
 }
 
 func simpleReceiveMessageAsync() {
-	sqs.SimpleReceiveMessageAsync(os.Getenv("SQS_QUEUE_TEST_URL"), handler, option.NewConsumer().SetDebugMode(true))
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	select {
-	case <-c:
-		logger.Info("Stopped application!")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	consumer := sqs.SimpleReceiveMessageAsync(ctx, os.Getenv("SQS_QUEUE_TEST_URL"), handler,
+		option.NewConsumer().SetDebugMode(true))
+	if err := consumer.Wait(); err != nil {
+		logger.Error("Consumer stopped with error:", err)
+		return
+	}
+	logger.Info("Stopped application!")
+}
+
+func receiveMessageBatch() {
+	_ = sqs.ReceiveMessageBatch(context.TODO(), os.Getenv("SQS_QUEUE_TEST_URL"), handlerBatch)
+}
+
+func receive() {
+	queueUrl := os.Getenv("SQS_QUEUE_TEST_URL")
+	opt := option.NewConsumer().SetWaitTimeSeconds(20 * time.Second).SetMaxNumberOfMessages(5)
+	output, err := sqs.SimpleReceive[test](context.TODO(), queueUrl, opt)
+	if err != nil {
+		logger.Error("error receive messages:", err)
+		return
+	}
+	for _, failed := range output.Failed {
+		logger.Error("message not converted:", failed)
+	}
+	for _, message := range output.Messages {
+		logger.Debug("message to process:", message)
+		_, _ = sqs.DeleteMessage(context.TODO(), queueUrl, message.ReceiptHandle)
 	}
 }
 
@@ -106,3 +169,15 @@ func handlerReceiveMessage(ctx *sqs.Context[string, messageAttTest]) error {
 	logger.Debug("ctx to process message:", ctx)
 	return nil
 }
+
+func handlerBatch(ctx *sqs.BatchContext[test, messageAttTest]) ([]string, error) {
+	var failedIds []string
+	for _, message := range ctx.Messages {
+		if len(message.Body.Name) == 0 {
+			failedIds = append(failedIds, message.Id)
+			continue
+		}
+		logger.Debug("message to process in batch:", message)
+	}
+	return failedIds, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/codec"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
 	"github.com/GabrielHCataldo/go-logger/logger"
 	"os"
@@ -42,6 +43,8 @@ func main() {
 	simpleAsync()
 	structBody()
 	mapBody()
+	batch()
+	asyncProducer()
 	completeOptions()
 }
 
@@ -88,6 +91,59 @@ func mapBody() {
 	}
 }
 
+func batch() {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	entries := []sqs.SendMessageBatchEntry{
+		{Body: initTestStruct()},
+		{Body: initTestStruct(), Opts: []*option.Producer{option.NewProducer().SetDelaySeconds(5 * time.Second)}},
+	}
+	output, err := sqs.SendMessageBatch(ctx, os.Getenv("SQS_QUEUE_TEST_URL"), entries)
+	if err != nil {
+		logger.Error("error send messages batch:", err)
+	}
+	if output != nil {
+		for _, result := range output.Results {
+			logger.Info("entry:", result.Index, "message id:", result.MessageId, "error:", result.Err)
+		}
+	}
+}
+
+func asyncProducer() {
+	opt := option.NewAsyncProducer().
+		// HTTP communication customization options with AWS SQS
+		SetHttpClient(option.HttpClient{}).
+		// print logs (default: false)
+		SetDebugMode(true).
+		// maximum number of messages of the same queue in each batch, 1 to 10 (default: 10)
+		SetBatchSize(10).
+		// maximum time a message waits for the batch to fill up (default: 100 milliseconds)
+		SetLinger(100 * time.Millisecond).
+		// maximum number of messages accepted and not yet delivered, Send blocks when reached (default: 1000)
+		SetMaxBufferedMessages(1000).
+		// maximum time of each batch request (default: 30 seconds)
+		SetSendTimeout(30 * time.Second)
+	producer := sqs.NewAsyncProducer(func(delivery *sqs.Delivery) {
+		if delivery.Err != nil {
+			logger.Error("error send message:", delivery.Err)
+		} else {
+			logger.Info("message sent successfully:", delivery.MessageId)
+		}
+	}, opt)
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 100; i++ {
+		err := producer.Send(ctx, os.Getenv("SQS_QUEUE_TEST_URL"), initTestStruct())
+		if err != nil {
+			logger.Error("error send message:", err)
+		}
+	}
+	err := producer.Close(ctx)
+	if err != nil {
+		logger.Error("error close async producer:", err)
+	}
+}
+
 func completeOptions() {
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
 	defer cancel()
@@ -106,7 +162,11 @@ func completeOptions() {
 		// This parameter applies only to FIFO (first-in-first-out) queues. The token used for deduplication of sent messages.
 		SetMessageDeduplicationId("").
 		// This parameter applies only to FIFO (first-in-first-out) queues. The tag that specifies that a message belongs to a specific message group.
-		SetMessageGroupId("")
+		SetMessageGroupId("").
+		// maximum attempts to send the failed entries of SendMessageBatch (default: 3)
+		SetBatchMaxAttempts(3).
+		// codec of the body, its content type is sent in the ContentType attribute (default: nil, converted by type)
+		SetCodec(codec.JSON)
 	message, err := sqs.SendMessage(ctx, os.Getenv("SQS_QUEUE_TEST_URL"), body, opt)
 	if err != nil {
 		logger.Error("error send message:", err)
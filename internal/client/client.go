@@ -4,18 +4,22 @@ import (
 	"context"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"sync"
 )
 
 var sqsClient *sqs.Client
+var mutex sync.Mutex
 
-func GetClient(ctx context.Context) *sqs.Client {
+func GetClient(ctx context.Context) (*sqs.Client, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
 	if sqsClient != nil {
-		return sqsClient
+		return sqsClient, nil
 	}
 	cfg, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	sqsClient = sqs.NewFromConfig(cfg)
-	return sqsClient
+	return sqsClient, nil
 }
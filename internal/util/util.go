@@ -1,9 +1,13 @@
 package util
 
 import (
+	crand "crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"math"
+	"math/rand"
 	"reflect"
 	"strconv"
 	"strings"
@@ -32,6 +36,9 @@ func ConvertToString(a any) string {
 
 func GetDataType(a any) string {
 	t := reflect.TypeOf(a)
+	if IsBytesType(t) {
+		return "Binary"
+	}
 	switch t.Kind() {
 	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface, reflect.String, reflect.Bool:
 		return "String"
@@ -43,6 +50,53 @@ func GetDataType(a any) string {
 	}
 }
 
+// GetBaseDataType returns the data type without the custom type suffix, e.g. Number for Number.float.
+func GetBaseDataType(dataType string) string {
+	baseDataType, _, _ := strings.Cut(dataType, ".")
+	return baseDataType
+}
+
+// IsBytesType returns true if t is a slice or an array of bytes, which are sent as Binary message attributes.
+func IsBytesType(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+}
+
+// ConvertToBytes returns the bytes of a slice or an array of bytes.
+func ConvertToBytes(v reflect.Value) []byte {
+	if v.Kind() == reflect.Array {
+		result := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(result), v)
+		return result
+	}
+	return v.Bytes()
+}
+
+// SqsTag represents the options of the sqs struct tag, e.g. `sqs:"name,type=Number.float,omitempty,required"`.
+type SqsTag struct {
+	Name      string
+	DataType  string
+	OmitEmpty bool
+	Required  bool
+}
+
+func GetSqsTag(tag string) SqsTag {
+	var result SqsTag
+	splitTag := strings.Split(tag, ",")
+	result.Name = strings.TrimSpace(splitTag[0])
+	for _, opt := range splitTag[1:] {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "omitempty":
+			result.OmitEmpty = true
+		case opt == "required":
+			result.Required = true
+		case strings.HasPrefix(opt, "type="):
+			result.DataType = strings.TrimPrefix(opt, "type=")
+		}
+	}
+	return result
+}
+
 func GetJsonNameByTag(tag string) string {
 	result := ""
 	splitTag := strings.Split(tag, ",")
@@ -78,7 +132,7 @@ func IsZeroReflect(v reflect.Value) bool {
 
 func IsNonZeroMessageAttValue(v *types.MessageAttributeValue) bool {
 	return v != nil && v.DataType != nil &&
-		(v.StringValue != nil && len(*v.StringValue) != 0)
+		((v.StringValue != nil && len(*v.StringValue) != 0) || len(v.BinaryValue) != 0)
 }
 
 func ParseStringToGeneric[T any](s string, dest *T) {
@@ -148,6 +202,32 @@ func ConvertDurationToInt32(d time.Duration) int32 {
 	return int32(d.Seconds())
 }
 
+func CalculateBackoff(attempt int, initial, max time.Duration, multiplier, jitter float64) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if d > float64(max) || math.IsInf(d, 0) || math.IsNaN(d) {
+		d = float64(max)
+	}
+	if jitter > 0 {
+		d += d * jitter * (rand.Float64()*2 - 1)
+	}
+	if d > float64(max) {
+		d = float64(max)
+	} else if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+func NewUUID() string {
+	b := make([]byte, 16)
+	_, _ = crand.Read(b)
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
 func convertToStringByType(a any) string {
 	switch t := a.(type) {
 	case int:
@@ -0,0 +1,91 @@
+package sqs
+
+import (
+	"context"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/client"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// API is the set of AWS SQS operations used by the package, it's implemented by *sqs.Client from the AWS SDK and
+// can be implemented by a mock in unit tests.
+type API interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (
+		*sqs.SendMessageOutput, error)
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (
+		*sqs.SendMessageBatchOutput, error)
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (
+		*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (
+		*sqs.DeleteMessageOutput, error)
+	DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (
+		*sqs.DeleteMessageBatchOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput,
+		optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+	ChangeMessageVisibilityBatch(ctx context.Context, params *sqs.ChangeMessageVisibilityBatchInput,
+		optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityBatchOutput, error)
+	StartMessageMoveTask(ctx context.Context, params *sqs.StartMessageMoveTaskInput, optFns ...func(*sqs.Options)) (
+		*sqs.StartMessageMoveTaskOutput, error)
+	CancelMessageMoveTask(ctx context.Context, params *sqs.CancelMessageMoveTaskInput, optFns ...func(*sqs.Options)) (
+		*sqs.CancelMessageMoveTaskOutput, error)
+	ListMessageMoveTasks(ctx context.Context, params *sqs.ListMessageMoveTasksInput, optFns ...func(*sqs.Options)) (
+		*sqs.ListMessageMoveTasksOutput, error)
+	CreateQueue(ctx context.Context, params *sqs.CreateQueueInput, optFns ...func(*sqs.Options)) (
+		*sqs.CreateQueueOutput, error)
+	TagQueue(ctx context.Context, params *sqs.TagQueueInput, optFns ...func(*sqs.Options)) (
+		*sqs.TagQueueOutput, error)
+	SetQueueAttributes(ctx context.Context, params *sqs.SetQueueAttributesInput, optFns ...func(*sqs.Options)) (
+		*sqs.SetQueueAttributesOutput, error)
+	UntagQueue(ctx context.Context, params *sqs.UntagQueueInput, optFns ...func(*sqs.Options)) (
+		*sqs.UntagQueueOutput, error)
+	PurgeQueue(ctx context.Context, params *sqs.PurgeQueueInput, optFns ...func(*sqs.Options)) (
+		*sqs.PurgeQueueOutput, error)
+	DeleteQueue(ctx context.Context, params *sqs.DeleteQueueInput, optFns ...func(*sqs.Options)) (
+		*sqs.DeleteQueueOutput, error)
+	GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (
+		*sqs.GetQueueUrlOutput, error)
+	GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (
+		*sqs.GetQueueAttributesOutput, error)
+	ListQueues(ctx context.Context, params *sqs.ListQueuesInput, optFns ...func(*sqs.Options)) (
+		*sqs.ListQueuesOutput, error)
+	ListQueueTags(ctx context.Context, params *sqs.ListQueueTagsInput, optFns ...func(*sqs.Options)) (
+		*sqs.ListQueueTagsOutput, error)
+	ListDeadLetterSourceQueues(ctx context.Context, params *sqs.ListDeadLetterSourceQueuesInput,
+		optFns ...func(*sqs.Options)) (*sqs.ListDeadLetterSourceQueuesOutput, error)
+}
+
+// Client is the entry point to the producer, consumer, queue and message operations using a specific AWS SQS
+// client, allowing to work with several accounts or regions in the same process, or to inject a mock of API in unit
+// tests. The package functions, like SendMessage, use a default client created from config.LoadDefaultConfig.
+//
+// As Go does not allow generic methods, the consumer operations are available in the functions with the
+// WithClient suffix, like ReceiveMessageWithClient.
+type Client struct {
+	api API
+}
+
+var defaultClient = &Client{}
+
+// NewClient creates a Client from the aws.Config informed, the optFns are applied to the AWS SQS client options.
+func NewClient(cfg aws.Config, optFns ...func(*sqs.Options)) *Client {
+	return NewClientFromAPI(sqs.NewFromConfig(cfg, optFns...))
+}
+
+// NewClientFromAPI creates a Client using the api informed to call AWS SQS, such as a *sqs.Client or a mock.
+func NewClientFromAPI(api API) *Client {
+	return &Client{api: api}
+}
+
+func (c *Client) getApi(ctx context.Context) (API, error) {
+	if c != nil && c.api != nil {
+		return c.api, nil
+	}
+	return client.GetClient(ctx)
+}
+
+func getClient(c *Client) *Client {
+	if c != nil {
+		return c
+	}
+	return defaultClient
+}
@@ -0,0 +1,171 @@
+package sqs
+
+import (
+	"context"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/sqstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewClient(t *testing.T) {
+	c := NewClient(aws.Config{Region: "sa-east-1"})
+	if c == nil || c.api == nil {
+		t.Error("NewClient() returned client without api")
+	}
+}
+
+func TestClientSendMessage(t *testing.T) {
+	api := initMockApi()
+	c := NewClientFromAPI(api)
+	output, err := c.SendMessage(context.TODO(), "https://sqs.mock/queue", initTestStruct())
+	if err != nil {
+		t.Errorf("SendMessage() error = %v", err)
+		return
+	}
+	if aws.ToString(output.MessageId) != "1" || len(api.sendInputs) != 1 {
+		t.Errorf("SendMessage() output = %v, want message sent by the mock", output)
+	}
+}
+
+func TestReceiveMessageWithClient(t *testing.T) {
+	api := initMockApi()
+	c := NewClientFromAPI(api)
+	ctx, cancel := context.WithTimeout(context.TODO(), 3*time.Second)
+	defer cancel()
+	err := ReceiveMessageWithClient(ctx, c, "https://sqs.mock/queue", initHandleConsumer[test, messageAttTest],
+		option.NewConsumer().SetDeleteMessageProcessedSuccess(true).SetDelayQueryLoop(time.Second))
+	if err != nil {
+		t.Errorf("ReceiveMessageWithClient() error = %v", err)
+	}
+	if receiptHandles := api.deletedReceiptHandles(); len(receiptHandles) != 1 {
+		t.Errorf("ReceiveMessageWithClient() deleted = %v, want mock-receipt-handle", receiptHandles)
+	}
+}
+
+func TestClientWithFake(t *testing.T) {
+	ctx := context.TODO()
+	c := NewClientFromAPI(sqstest.NewFake())
+	dlqOutput, err := c.CreateQueue(ctx, "fake-dlq")
+	if err != nil {
+		t.Fatalf("CreateQueue() error = %v", err)
+	}
+	dlqAttributes, err := c.GetQueueAttributes(ctx, GetQueueAttributesInput{
+		QueueUrl:       *dlqOutput.QueueUrl,
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameQueueArn},
+	})
+	if err != nil {
+		t.Fatalf("GetQueueAttributes() error = %v", err)
+	}
+	output, err := c.CreateQueue(ctx, "fake", option.NewCreateQueue().SetAttributes(map[string]string{
+		"VisibilityTimeout": "1",
+		"RedrivePolicy":     `{"deadLetterTargetArn":"` + dlqAttributes.Attributes["QueueArn"] + `","maxReceiveCount":1}`,
+	}))
+	if err != nil {
+		t.Fatalf("CreateQueue() error = %v", err)
+	}
+	queueUrl := *output.QueueUrl
+	for _, handler := range []HandlerConsumerFunc[test, messageAttTest]{
+		initHandleConsumer[test, messageAttTest],
+		initHandleConsumerWithErr[test, messageAttTest],
+	} {
+		_, err = c.SendMessage(ctx, queueUrl, initTestStruct(),
+			option.NewProducer().SetMessageAttributes(initMessageAttTest()))
+		if err != nil {
+			t.Fatalf("SendMessage() error = %v", err)
+		}
+		ctxConsumer, cancel := context.WithTimeout(ctx, 3*time.Second)
+		err = ReceiveMessageWithClient(ctxConsumer, c, queueUrl, handler, option.NewConsumer().
+			SetDeleteMessageProcessedSuccess(true).
+			SetDeleteBuffer(option.DeleteBuffer{FlushInterval: 100 * time.Millisecond}).
+			SetDelayQueryLoop(500*time.Millisecond))
+		cancel()
+		if err != nil {
+			t.Errorf("ReceiveMessageWithClient() error = %v", err)
+		}
+	}
+	for queueUrl, want := range map[string]string{queueUrl: "0", *dlqOutput.QueueUrl: "1"} {
+		attributes, err := c.GetQueueAttributes(ctx, GetQueueAttributesInput{
+			QueueUrl:       queueUrl,
+			AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameApproximateNumberOfMessages},
+		})
+		if err != nil || attributes.Attributes["ApproximateNumberOfMessages"] != want {
+			t.Errorf("GetQueueAttributes() %s attributes = %v, err = %v, want %s messages", queueUrl,
+				attributes.Attributes, err, want)
+		}
+	}
+}
+
+func TestClientWithServer(t *testing.T) {
+	server := sqstest.NewServer()
+	defer server.Close()
+	queueUrl := server.Fake.NewQueue("server", nil)
+	c := NewClient(server.Config())
+	ctx, cancel := context.WithTimeout(context.TODO(), 2*time.Second)
+	defer cancel()
+	_, err := c.SendMessage(ctx, queueUrl, initTestStruct(),
+		option.NewProducer().SetMessageAttributes(initMessageAttTest()))
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	err = ReceiveMessageWithClient(ctx, c, queueUrl, initHandleConsumer[test, messageAttTest], option.NewConsumer().
+		SetDeleteMessageProcessedSuccess(true).
+		SetDelayQueryLoop(500*time.Millisecond))
+	if err != nil {
+		t.Errorf("ReceiveMessageWithClient() error = %v", err)
+	}
+	if messages := server.Fake.Messages(queueUrl); len(messages) != 0 {
+		t.Errorf("ReceiveMessageWithClient() messages = %v, want deleted", messages)
+	}
+}
+
+func TestFuncByHttpClient(t *testing.T) {
+	options := sqs.Options{
+		AppID:      "app",
+		Region:     "sa-east-1",
+		APIOptions: initApiOptions(),
+	}
+	option.FuncByHttpClient(&option.HttpClient{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String("http://localhost:4566"),
+		RetryMaxAttempts: 5,
+		APIOptions:       initApiOptions(),
+	})(&options)
+	if options.Region != "us-east-1" || aws.ToString(options.BaseEndpoint) != "http://localhost:4566" ||
+		options.RetryMaxAttempts != 5 {
+		t.Errorf("FuncByHttpClient() non-zero fields not applied: %+v", options)
+	}
+	if options.AppID != "app" || len(options.APIOptions) != 2 {
+		t.Errorf("FuncByHttpClient() zero fields must not override the client options: %+v", options)
+	}
+	option.FuncByHttpClient(nil)(&options)
+}
+
+func TestHttpClientBaseEndpoint(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if target := r.Header.Get("X-Amz-Target"); target != "AmazonSQS.GetQueueUrl" {
+			t.Errorf("unexpected target %s", target)
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		_, _ = w.Write([]byte(`{"QueueUrl":"http://localhost/000000000000/test"}`))
+	}))
+	defer server.Close()
+	c := NewClient(initAwsConfigTest())
+	output, err := c.GetQueueUrl(context.TODO(), GetQueueUrlInput{QueueName: "test"},
+		option.NewDefault().SetHttpClient(option.HttpClient{BaseEndpoint: aws.String(server.URL)}))
+	if err != nil {
+		t.Errorf("GetQueueUrl() error = %v", err)
+		return
+	}
+	if calls.Load() != 1 || aws.ToString(output.QueueUrl) != "http://localhost/000000000000/test" {
+		t.Errorf("GetQueueUrl() custom endpoint not called, calls = %d output = %v", calls.Load(), output)
+	}
+}
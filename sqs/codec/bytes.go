@@ -0,0 +1,46 @@
+package codec
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Bytes is the codec that sends the raw bytes of the body encoded in base64, the values must be []byte or string, and
+// the destination *[]byte or *string.
+var Bytes Codec = bytesCodec{}
+
+var errNotBytes = errors.New("codec: value is not []byte or string")
+
+type bytesCodec struct{}
+
+func (bytesCodec) ContentType() string {
+	return "application/octet-stream"
+}
+
+func (bytesCodec) Marshal(v any) (string, error) {
+	switch t := v.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(t), nil
+	case string:
+		return base64.StdEncoding.EncodeToString([]byte(t)), nil
+	default:
+		return "", fmt.Errorf("%w: %T", errNotBytes, v)
+	}
+}
+
+func (bytesCodec) Unmarshal(data string, v any) error {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case *[]byte:
+		*t = b
+	case *string:
+		*t = string(b)
+	default:
+		return fmt.Errorf("%w: %T", errNotBytes, v)
+	}
+	return nil
+}
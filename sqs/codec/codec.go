@@ -0,0 +1,70 @@
+// Package codec provides the encoders and decoders of the message body, a Codec can be set in option.Producer and
+// option.Consumer. The producer writes the content type of the codec in the ContentTypeAttributeName message
+// attribute, so the consumer can pick the matching codec automatically.
+package codec
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// ContentTypeAttributeName is the name of the message attribute with the content type of the body.
+const ContentTypeAttributeName = "ContentType"
+
+// Codec converts the body of the messages, as the body of an SQS message is a text, binary formats must be encoded
+// to text, e.g. with base64.
+type Codec interface {
+	// ContentType returns the content type written in the ContentTypeAttributeName message attribute,
+	// e.g. application/json.
+	ContentType() string
+	// Marshal converts v to the text of the message body.
+	Marshal(v any) (string, error)
+	// Unmarshal converts the text of the message body to v, v must be a pointer.
+	Unmarshal(data string, v any) error
+}
+
+var registry = map[string]Codec{}
+var registryMutex sync.RWMutex
+
+func init() {
+	Register(JSON)
+	Register(Protobuf)
+	Register(Msgpack)
+	Register(Bytes)
+}
+
+// Register registers c to be found by Get with its content type, replacing the codec previously registered with the
+// same content type. The JSON, Protobuf, Msgpack and Bytes codecs are registered by default.
+func Register(c Codec) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[c.ContentType()] = c
+}
+
+// Get returns the codec registered with the contentType, or nil if there is none.
+func Get(contentType string) Codec {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return registry[contentType]
+}
+
+// JSON is the codec that converts the body with encoding/json.
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Marshal(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (jsonCodec) Unmarshal(data string, v any) error {
+	return json.Unmarshal([]byte(data), v)
+}
@@ -0,0 +1,91 @@
+package codec
+
+import (
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"reflect"
+	"testing"
+)
+
+type testCodec struct {
+	name    string
+	codec   Codec
+	value   any
+	dest    any
+	want    any
+	wantErr bool
+}
+
+type test struct {
+	Name  string
+	Count int
+}
+
+func initListTestCodec() []testCodec {
+	var destStruct test
+	var destProto *wrapperspb.StringValue
+	var destBytes []byte
+	var destInvalid string
+	return []testCodec{
+		{
+			name:  "json",
+			codec: JSON,
+			value: test{Name: "name test", Count: 1},
+			dest:  &destStruct,
+			want:  test{Name: "name test", Count: 1},
+		},
+		{
+			name:  "msgpack",
+			codec: Msgpack,
+			value: test{Name: "name test", Count: 2},
+			dest:  &destStruct,
+			want:  test{Name: "name test", Count: 2},
+		},
+		{
+			name:  "protobuf",
+			codec: Protobuf,
+			value: wrapperspb.String("proto test"),
+			dest:  &destProto,
+			want:  "proto test",
+		},
+		{
+			name:  "bytes",
+			codec: Bytes,
+			value: []byte("bytes test"),
+			dest:  &destBytes,
+			want:  []byte("bytes test"),
+		},
+		{
+			name:    "protobuf invalid value",
+			codec:   Protobuf,
+			value:   test{},
+			dest:    &destInvalid,
+			wantErr: true,
+		},
+	}
+}
+
+func TestCodec(t *testing.T) {
+	for _, tt := range initListTestCodec() {
+		t.Run(tt.name, func(t *testing.T) {
+			if c := Get(tt.codec.ContentType()); c != tt.codec {
+				t.Errorf("Get() = %v, want %v", c, tt.codec)
+			}
+			data, err := tt.codec.Marshal(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Marshal() error = %v, wantErr %v", err, tt.wantErr)
+			} else if err != nil {
+				return
+			}
+			if err = tt.codec.Unmarshal(data, tt.dest); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			got := reflect.ValueOf(tt.dest).Elem().Interface()
+			if m, ok := got.(*wrapperspb.StringValue); ok {
+				got = m.GetValue()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package codec
+
+import (
+	"encoding/base64"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Msgpack is the codec that converts the body with MessagePack encoded in base64.
+var Msgpack Codec = msgpackCodec{}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string {
+	return "application/x-msgpack"
+}
+
+func (msgpackCodec) Marshal(v any) (string, error) {
+	b, err := msgpack.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func (msgpackCodec) Unmarshal(data string, v any) error {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return err
+	}
+	return msgpack.Unmarshal(b, v)
+}
@@ -0,0 +1,58 @@
+package codec
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"reflect"
+)
+
+// Protobuf is the codec that converts the body with protocol buffers encoded in base64, the values must implement
+// proto.Message, or be a pointer to a type implementing it.
+var Protobuf Codec = protobufCodec{}
+
+var errNotProtoMessage = errors.New("codec: value does not implement proto.Message")
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (protobufCodec) Marshal(v any) (string, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return "", fmt.Errorf("%w: %T", errNotProtoMessage, v)
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func (protobufCodec) Unmarshal(data string, v any) error {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return err
+	}
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(b, m)
+	}
+	// v is a pointer to a pointer of message, e.g. **pb.Message when the body type is *pb.Message
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Pointer {
+		return fmt.Errorf("%w: %T", errNotProtoMessage, v)
+	}
+	elem := reflect.New(rv.Elem().Type().Elem())
+	m, ok := elem.Interface().(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T", errNotProtoMessage, v)
+	}
+	if err = proto.Unmarshal(b, m); err != nil {
+		return err
+	}
+	rv.Elem().Set(elem)
+	return nil
+}
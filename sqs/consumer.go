@@ -2,13 +2,19 @@ package sqs
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/client"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/util"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/codec"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -77,6 +83,8 @@ type Context[Body, MessageAttributes any] struct {
 	QueueUrl string
 	// converted message to process
 	Message MessageReceived[Body, MessageAttributes]
+	// state of the manual acknowledgement of the message (see Ack, Nack and ExtendVisibility)
+	settlement *messageSettlement
 }
 
 // SimpleContext represents the execution context of a consumer handler in the package.
@@ -89,12 +97,31 @@ type HandlerConsumerFunc[Body, MessageAttributes any] func(ctx *Context[Body, Me
 // HandlerSimpleConsumerFunc is a function that consumes a message and returns an error if a failure occurs while processing the message.
 type HandlerSimpleConsumerFunc[Body any] func(ctx *SimpleContext[Body]) error
 
+// ReceiveOutput represents the messages received by Receive.
+type ReceiveOutput[Body, MessageAttributes any] struct {
+	// Messages received and converted, they stay invisible in the queue until the visibility timeout, so they must be
+	// settled by the caller, using DeleteMessage or ChangeMessageVisibility with their receipt handle.
+	Messages []MessageReceived[Body, MessageAttributes]
+	// Messages received which could not be converted, in the same order they were received.
+	Failed []*DecodeMessageError
+}
+
+type messagesProcessor func(output *sqs.ReceiveMessageOutput, job *consumerJob, workers *workerReservation)
+
 type channelMessageProcessed struct {
 	Err    error
 	Signal *chan struct{}
 }
 
-var ctxInterrupt context.Context
+// Consumer is the handle of a consumer job started by the receive functions, it's used to stop the job and to
+// wait until it's completely finished.
+type Consumer struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+	err    error
+}
 
 // ReceiveMessage Works as a repeating job, when triggered, it will fetch messages from the indicated queue
 // in the queueUrl parameter, if it does not find any messages it will reprocess the function looking for new messages again,
@@ -106,22 +133,48 @@ var ctxInterrupt context.Context
 // (option.Consumer.DeleteMessageProcessedSuccess) if you have it enabled, we will remove the message from the file for you.
 // finally, when processing all messages, we return to the initial flow looking for new messages.
 //
+// The messages are processed by a pool of workers shared by all pollers (option.Consumer.Pollers), with up to
+// option.Consumer.MaxInFlightMessages handlers running at the same time, when all workers are busy the pollers
+// wait for a free worker before searching for new messages, and each search requests no more messages than the free
+// workers.
+//
 // # Parameters
 //
+// - ctx: context of the job, when it is canceled the consumer stops gracefully (see Consumer.Stop)
 // - queueUrl: url of the queue where you want to fetch messages
 // - handler: function to process the received message
 // - opts: list of option.Consumer to customize the job
 //
-// # Panic
+// # Errors
 //
-// If 3 errors occur when obtaining the message from the queue, it will trigger a panic informing the error returned
-// from AWS SQS.
+// Failures to receive messages from the queue are retried following the option.Consumer.ReceiveErrorPolicy and
+// reported to option.Consumer.OnError, if the policy gives up, the job is stopped returning the error wrapped in
+// ErrReceiveMessageAttemptsExceeded. If the AWS SQS client cannot be created, the job is stopped returning the error.
+//
+// # Returns
+//
+// - error: the error that stopped the job, nil if it was stopped by the ctx or Consumer.Stop
 func ReceiveMessage[Body, MessageAttributes any](
+	ctx context.Context,
 	queueUrl string,
 	handler HandlerConsumerFunc[Body, MessageAttributes],
 	opts ...*option.Consumer,
-) {
-	receiveMessage(queueUrl, handler, option.GetConsumerByParams(opts))
+) error {
+	return ReceiveMessageWithClient[Body, MessageAttributes](ctx, nil, queueUrl, handler, opts...)
+}
+
+// ReceiveMessageWithClient works like ReceiveMessage, using the client c instead of the default client, if c is nil the
+// default client is used.
+func ReceiveMessageWithClient[Body, MessageAttributes any](
+	ctx context.Context,
+	c *Client,
+	queueUrl string,
+	handler HandlerConsumerFunc[Body, MessageAttributes],
+	opts ...*option.Consumer,
+) error {
+	consumer := newConsumer(ctx)
+	receiveMessage(consumer, c, queueUrl, handler, option.GetConsumerByParams(opts))
+	return consumer.Err()
 }
 
 // ReceiveMessageAsync Works like a repeating job, when triggered, it will fetch messages from the indicated queue
@@ -138,20 +191,41 @@ func ReceiveMessage[Body, MessageAttributes any](
 //
 // # Parameters
 //
+// - ctx: context of the job, when it is canceled the consumer stops gracefully (see Consumer.Stop)
 // - queueUrl: url of the queue where you want to fetch messages
 // - handler: function to process the received message
 // - opts: list of options.Consumer to customize the job
 //
-// # Panic
+// # Errors
+//
+// Failures to receive messages from the queue are retried following the option.Consumer.ReceiveErrorPolicy and
+// reported to option.Consumer.OnError, if the policy gives up, the job is stopped returning the error wrapped in
+// ErrReceiveMessageAttemptsExceeded. If the AWS SQS client cannot be created, the job is stopped returning the error.
 //
-// If 3 errors occur when obtaining the message from the queue, it will trigger a panic informing the error returned
-// from AWS SQS.
+// # Returns
+//
+// - *Consumer: handle to stop and wait for the job
 func ReceiveMessageAsync[Body, MessageAttributes any](
+	ctx context.Context,
 	queueUrl string,
 	handler HandlerConsumerFunc[Body, MessageAttributes],
 	opts ...*option.Consumer,
-) {
-	go receiveMessage(queueUrl, handler, option.GetConsumerByParams(opts))
+) *Consumer {
+	return ReceiveMessageAsyncWithClient[Body, MessageAttributes](ctx, nil, queueUrl, handler, opts...)
+}
+
+// ReceiveMessageAsyncWithClient works like ReceiveMessageAsync, using the client c instead of the default client, if c
+// is nil the default client is used.
+func ReceiveMessageAsyncWithClient[Body, MessageAttributes any](
+	ctx context.Context,
+	c *Client,
+	queueUrl string,
+	handler HandlerConsumerFunc[Body, MessageAttributes],
+	opts ...*option.Consumer,
+) *Consumer {
+	consumer := newConsumer(ctx)
+	go receiveMessage(consumer, c, queueUrl, handler, option.GetConsumerByParams(opts))
+	return consumer
 }
 
 // SimpleReceiveMessage Works as a repeating job, when triggered, it will fetch messages from the indicated queue
@@ -165,21 +239,42 @@ func ReceiveMessageAsync[Body, MessageAttributes any](
 //
 // # Parameters
 //
+// - ctx: context of the job, when it is canceled the consumer stops gracefully (see Consumer.Stop)
 // - queueUrl: url of the queue where you want to fetch messages
 // - handler: function to process the received message
 // - opts: list of options.Consumer to customize the job
 //
-// # Panic
+// # Errors
+//
+// Failures to receive messages from the queue are retried following the option.Consumer.ReceiveErrorPolicy and
+// reported to option.Consumer.OnError, if the policy gives up, the job is stopped returning the error wrapped in
+// ErrReceiveMessageAttemptsExceeded. If the AWS SQS client cannot be created, the job is stopped returning the error.
+//
+// # Returns
 //
-// If 3 errors occur when obtaining the message from the queue, it will trigger a panic informing the error returned
-// from AWS SQS.
+// - error: the error that stopped the job, nil if it was stopped by the ctx or Consumer.Stop
 func SimpleReceiveMessage[Body any](
+	ctx context.Context,
 	queueUrl string,
 	simpleHandle HandlerSimpleConsumerFunc[Body],
 	opts ...*option.Consumer,
-) {
+) error {
+	return SimpleReceiveMessageWithClient[Body](ctx, nil, queueUrl, simpleHandle, opts...)
+}
+
+// SimpleReceiveMessageWithClient works like SimpleReceiveMessage, using the client c instead of the default client, if
+// c is nil the default client is used.
+func SimpleReceiveMessageWithClient[Body any](
+	ctx context.Context,
+	c *Client,
+	queueUrl string,
+	simpleHandle HandlerSimpleConsumerFunc[Body],
+	opts ...*option.Consumer,
+) error {
+	consumer := newConsumer(ctx)
 	handler := initHandleConsumerFunc(simpleHandle)
-	receiveMessage(queueUrl, handler, option.GetConsumerByParams(opts))
+	receiveMessage(consumer, c, queueUrl, handler, option.GetConsumerByParams(opts))
+	return consumer.Err()
 }
 
 // SimpleReceiveMessageAsync Works like a repeating job, when triggered, it will fetch messages from the indicated queue
@@ -195,55 +290,265 @@ func SimpleReceiveMessage[Body any](
 //
 // # Parameters
 //
+// - ctx: context of the job, when it is canceled the consumer stops gracefully (see Consumer.Stop)
 // - queueUrl: url of the queue where you want to fetch messages
 // - handler: function to process the received message
 // - opts: list of options.Consumer to customize the job
 //
-// # Panic
+// # Errors
+//
+// Failures to receive messages from the queue are retried following the option.Consumer.ReceiveErrorPolicy and
+// reported to option.Consumer.OnError, if the policy gives up, the job is stopped returning the error wrapped in
+// ErrReceiveMessageAttemptsExceeded. If the AWS SQS client cannot be created, the job is stopped returning the error.
 //
-// If 3 errors occur when obtaining the message from the queue, it will trigger a panic informing the error returned
-// from AWS SQS.
+// # Returns
+//
+// - *Consumer: handle to stop and wait for the job
 func SimpleReceiveMessageAsync[Body any](
+	ctx context.Context,
 	queueUrl string,
 	simpleHandle HandlerSimpleConsumerFunc[Body],
 	opts ...*option.Consumer,
-) {
+) *Consumer {
+	return SimpleReceiveMessageAsyncWithClient[Body](ctx, nil, queueUrl, simpleHandle, opts...)
+}
+
+// SimpleReceiveMessageAsyncWithClient works like SimpleReceiveMessageAsync, using the client c instead of the default
+// client, if c is nil the default client is used.
+func SimpleReceiveMessageAsyncWithClient[Body any](
+	ctx context.Context,
+	c *Client,
+	queueUrl string,
+	simpleHandle HandlerSimpleConsumerFunc[Body],
+	opts ...*option.Consumer,
+) *Consumer {
+	consumer := newConsumer(ctx)
 	handler := initHandleConsumerFunc(simpleHandle)
-	go receiveMessage(queueUrl, handler, option.GetConsumerByParams(opts))
+	go receiveMessage(consumer, c, queueUrl, handler, option.GetConsumerByParams(opts))
+	return consumer
+}
+
+// Receive searches the queue for messages once, a pull-style alternative to ReceiveMessage for cron jobs, CLI tools and
+// request-scoped code which process a bounded number of messages. It makes a single request waiting up to
+// option.Consumer.WaitTimeSeconds (long polling) and returns up to option.Consumer.MaxNumberOfMessages messages,
+// converted to the types of Body and MessageAttributes the same way as in ReceiveMessage, if you don't use
+// MessageAttributes, you can use the SimpleReceive function.
+//
+// The messages are not settled, they stay invisible in the queue until the visibility timeout
+// (option.Consumer.VisibilityTimeout), so the caller must remove them with DeleteMessage after processing, or make them
+// visible again with ChangeMessageVisibility, using the MessageReceived.ReceiptHandle. The options of the handlers,
+// like option.Consumer.DeleteMessageProcessedSuccess and option.Consumer.Middlewares, are not used.
+//
+// Example usage:
+//
+//	opt := option.NewConsumer().SetWaitTimeSeconds(20 * time.Second)
+//	output, err := sqs.Receive[test, testAttributes](ctx, queueUrl, opt)
+//	for _, message := range output.Messages {
+//		_, err = sqs.DeleteMessage(ctx, queueUrl, message.ReceiptHandle)
+//	}
+//
+// # Parameters
+//
+// - ctx: context of the request
+// - queueUrl: url of the queue where you want to fetch messages
+// - opts: list of option.Consumer to customize the request
+//
+// # Returns
+//
+// - output: the messages converted and the messages which failed to be converted, each with its DecodeMessageError,
+// the failed messages are not settled either
+// - error: error returned from AWS SQS
+func Receive[Body, MessageAttributes any](ctx context.Context, queueUrl string, opts ...*option.Consumer) (
+	*ReceiveOutput[Body, MessageAttributes], error) {
+	return ReceiveWithClient[Body, MessageAttributes](ctx, nil, queueUrl, opts...)
+}
+
+// ReceiveWithClient works like Receive, using the client c instead of the default client, if c is nil the default
+// client is used.
+func ReceiveWithClient[Body, MessageAttributes any](ctx context.Context, c *Client, queueUrl string,
+	opts ...*option.Consumer) (*ReceiveOutput[Body, MessageAttributes], error) {
+	return receiveMessageOnce[Body, MessageAttributes](ctx, c, queueUrl, option.GetConsumerByParams(opts))
+}
+
+// SimpleReceive works like Receive, keeping the message attributes as received from AWS SQS.
+func SimpleReceive[Body any](ctx context.Context, queueUrl string, opts ...*option.Consumer) (
+	*ReceiveOutput[Body, map[string]types.MessageAttributeValue], error) {
+	return SimpleReceiveWithClient[Body](ctx, nil, queueUrl, opts...)
+}
+
+// SimpleReceiveWithClient works like SimpleReceive, using the client c instead of the default client, if c is nil the
+// default client is used.
+func SimpleReceiveWithClient[Body any](ctx context.Context, c *Client, queueUrl string, opts ...*option.Consumer) (
+	*ReceiveOutput[Body, map[string]types.MessageAttributeValue], error) {
+	return ReceiveWithClient[Body, map[string]types.MessageAttributeValue](ctx, c, queueUrl, opts...)
+}
+
+// Stop stops the consumer gracefully, the search for new messages is interrupted, the messages in process have up to
+// option.Consumer.DrainTimeout to finish and the pending deletions are sent before the job is finished. Stop does not
+// wait for the job, use Wait for that.
+func (c *Consumer) Stop() {
+	c.cancel()
+}
+
+// Wait blocks until the consumer is completely finished and returns the same as Err.
+func (c *Consumer) Wait() error {
+	<-c.done
+	return c.Err()
+}
+
+// Done returns a channel that is closed when the consumer is completely finished.
+func (c *Consumer) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error that stopped the consumer, nil if it's still running or if it was stopped by the
+// context or Stop.
+func (c *Consumer) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
 }
 
 func receiveMessage[Body, MessageAttributes any](
+	consumer *Consumer,
+	c *Client,
 	queueUrl string,
 	handler HandlerConsumerFunc[Body, MessageAttributes],
 	opt *option.Consumer,
 ) {
-	ctx := context.TODO()
-	if ctxInterrupt != nil {
-		ctx = ctxInterrupt
+	runConsumer(consumer, c, queueUrl, opt, false, func(output *sqs.ReceiveMessageOutput, job *consumerJob,
+		workers *workerReservation) {
+		processMessages[Body, MessageAttributes](queueUrl, output, handler, job, workers)
+	})
+}
+
+func receiveMessageOnce[Body, MessageAttributes any](
+	ctx context.Context,
+	c *Client,
+	queueUrl string,
+	opt *option.Consumer,
+) (*ReceiveOutput[Body, MessageAttributes], error) {
+	loggerInfo(opt.DebugMode, "getting client sqs..")
+	sqsClient, err := getClient(c).getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
+	input := prepareReceiveMessageInput(queueUrl, opt)
+	loggerInfo(opt.DebugMode, "receiving messages..")
+	output, err := sqsClient.ReceiveMessage(ctx, &input, option.FuncByHttpClient(opt.HttpClient))
+	if err != nil {
+		loggerErr(opt.DebugMode, "error receive messages:", err)
+		return nil, err
 	}
+	result := &ReceiveOutput[Body, MessageAttributes]{}
+	for _, message := range output.Messages {
+		messageReceived, err := prepareMessageReceived[Body, MessageAttributes](message, opt)
+		if err != nil {
+			result.Failed = append(result.Failed, &DecodeMessageError{QueueUrl: queueUrl, Message: message, Err: err})
+			continue
+		}
+		result.Messages = append(result.Messages, messageReceived)
+	}
+	loggerInfo(opt.DebugMode, "messages received successfully:", len(result.Messages), "failed:", len(result.Failed))
+	return result, nil
+}
+
+// runConsumer runs the pollers of the consumer until it's stopped, if batch is true each receive is processed by a
+// single worker, otherwise each message received is processed by its own worker.
+func runConsumer(
+	consumer *Consumer,
+	c *Client,
+	queueUrl string,
+	opt *option.Consumer,
+	batch bool,
+	process messagesProcessor,
+) {
+	defer consumer.finish(nil)
+	ctx := consumer.ctx
+	c = getClient(c)
 	ctxClient, cancelCtxClient := context.WithTimeout(ctx, 5*time.Second)
 	defer cancelCtxClient()
-	sqsClient := client.GetClient(ctxClient)
+	sqsClient, err := c.getApi(ctxClient)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		consumer.finish(err)
+		return
+	}
 	input := prepareReceiveMessageInput(queueUrl, opt)
-	attemptsReceiveMessages := 0
 	printLogInitial(opt)
+	ctxPollers, cancelPollers := context.WithCancel(ctx)
+	defer cancelPollers()
+	job := newConsumerJob(ctx, c, queueUrl, opt)
+	defer job.cancelHandlers()
+	errs := make(chan error, opt.Pollers)
+	for i := 0; i < opt.Pollers; i++ {
+		go func() {
+			errs <- pollMessages(ctxPollers, sqsClient, queueUrl, &input, batch, process, job)
+		}()
+	}
+	for i := 0; i < opt.Pollers; i++ {
+		if errPoller := <-errs; errPoller != nil && err == nil {
+			err = errPoller
+			cancelPollers()
+		}
+	}
+	job.shutdown()
+	consumer.finish(err)
+}
+
+// pollMessages searches for messages while there are free workers, the workers are reserved before each search, so
+// each message received has a worker waiting for it: one worker for the batch, or one worker per message, in this case
+// no more messages than the free workers are requested.
+func pollMessages(
+	ctx context.Context,
+	sqsClient API,
+	queueUrl string,
+	input *sqs.ReceiveMessageInput,
+	batch bool,
+	process messagesProcessor,
+	job *consumerJob,
+) error {
+	opt := job.opt
+	maxWorkers := int(input.MaxNumberOfMessages)
+	if batch {
+		maxWorkers = 1
+	}
+	attemptsReceiveMessages := 0
 	for {
-		if ctx.Err() != nil {
-			break
+		workers := job.workers.reserve(ctx, maxWorkers)
+		if workers == nil {
+			return nil
 		}
-		output, err := sqsClient.ReceiveMessage(ctx, &input)
-		if err != nil {
-			handleError(&attemptsReceiveMessages, err, opt)
+		inputReceive := *input
+		if !batch {
+			inputReceive.MaxNumberOfMessages = int32(workers.size)
+		}
+		output, err := sqsClient.ReceiveMessage(ctx, &inputReceive, option.FuncByHttpClient(opt.HttpClient))
+		if ctx.Err() != nil {
+			workers.release()
+			return nil
+		} else if err != nil {
+			workers.release()
+			attemptsReceiveMessages++
+			if err = handleReceiveError(ctx, queueUrl, attemptsReceiveMessages, err, job); err != nil {
+				return err
+			}
 			continue
 		}
+		attemptsReceiveMessages = 0
 		if len(output.Messages) == 0 {
+			workers.release()
 			loggerInfo(opt.DebugMode, "No msg available to be processed, searching again in", opt.DelayQueryLoop.String())
-			time.Sleep(opt.DelayQueryLoop)
+			sleep(ctx, opt.DelayQueryLoop)
 			continue
 		}
 		loggerInfo(opt.DebugMode, "Start process received messages size:", len(output.Messages))
-		processMessages[Body, MessageAttributes](queueUrl, output, handler, opt)
-		time.Sleep(opt.DelayQueryLoop)
+		process(output, job, workers)
+		workers.release()
 	}
 }
 
@@ -262,76 +567,117 @@ func prepareReceiveMessageInput(queueUrl string, opt *option.Consumer) sqs.Recei
 }
 
 func printLogInitial(opt *option.Consumer) {
-	loggerInfo(opt.DebugMode, "Run start find messages with options:", opt)
+	// the functions cannot be converted to JSON by the logger, so they are left out of the options logged
+	optLog := *opt
+	optLog.OnError = nil
+	optLog.OnPoisonMessage = nil
+	optLog.Middlewares = nil
+	loggerInfo(opt.DebugMode, "Run start find messages with options:", &optLog)
 }
 
-func handleError(attemptsReceiveMessages *int, err error, opt *option.Consumer) {
-	*attemptsReceiveMessages++
-	loggerErr(opt.DebugMode, "Receive message error:", err, " attempt:", attemptsReceiveMessages)
-	if *attemptsReceiveMessages >= 3 {
-		panic(fmt.Sprintln("Stop consumer: number of failed attempts exceeded 3 err:", err))
+func handleReceiveError(ctx context.Context, queueUrl string, attempt int, err error, job *consumerJob) error {
+	opt := job.opt
+	policy := opt.ReceiveErrorPolicy
+	errReceive := &ReceiveMessageError{
+		QueueUrl: queueUrl,
+		Attempt:  attempt,
+		Err:      err,
+	}
+	loggerErr(opt.DebugMode, "Receive message error:", err, " attempt:", attempt)
+	job.reportError(errReceive)
+	if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+		loggerErr(opt.DebugMode, "Stop consumer: number of failed attempts exceeded", policy.MaxAttempts)
+		return fmt.Errorf("%w: %w", ErrReceiveMessageAttemptsExceeded, errReceive)
 	}
-	loggerInfo(opt.DebugMode, "Trying again in", opt.DelayQueryLoop.String())
-	time.Sleep(opt.DelayQueryLoop)
+	delay := util.CalculateBackoff(attempt, policy.Initial, policy.Max, policy.Multiplier, policy.Jitter)
+	loggerInfo(opt.DebugMode, "Trying again in", delay.String())
+	sleep(ctx, delay)
+	return nil
 }
 
 func processMessages[Body, MessageAttributes any](
 	queueUrl string,
 	output *sqs.ReceiveMessageOutput,
 	handler HandlerConsumerFunc[Body, MessageAttributes],
-	opt *option.Consumer,
+	job *consumerJob,
+	workers *workerReservation,
 ) {
+	opt := job.opt
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
 	var count int
 	var mgsS, mgsF []string
 	for _, message := range output.Messages {
-		mgsS, mgsF = processMessage(queueUrl, handler, message, opt)
-		count++
+		message := message
+		wg.Add(1)
+		workers.run(func() {
+			defer wg.Done()
+			s, f := processMessage(queueUrl, handler, message, job)
+			mutex.Lock()
+			defer mutex.Unlock()
+			mgsS = append(mgsS, s...)
+			mgsF = append(mgsF, f...)
+			count++
+		})
 	}
-	loggerInfo(opt.DebugMode, "Finish process messages!", "processed:", count, "success:", mgsS, "failed:", mgsF)
+	go func() {
+		wg.Wait()
+		loggerInfo(opt.DebugMode, "Finish process messages!", "processed:", count, "success:", mgsS, "failed:", mgsF)
+	}()
 }
 
 func processMessage[Body, MessageAttributes any](
 	queueUrl string,
 	handler HandlerConsumerFunc[Body, MessageAttributes],
 	message types.Message,
-	opt *option.Consumer,
+	job *consumerJob,
 ) (mgsS, mgsF []string) {
-	ctx, cancel := context.WithTimeout(context.TODO(), opt.ConsumerMessageTimeout)
+	opt := job.opt
+	ctx, cancel := context.WithTimeout(job.ctxHandlers, opt.ConsumerMessageTimeout)
 	defer cancel()
-	ctxConsumer, err := prepareContextConsumer[Body, MessageAttributes](ctx, queueUrl, message)
+	if err := job.checkMaxReceiveCount(message); err != nil {
+		job.reportPoisonMessage(queueUrl, message, err)
+		return
+	}
+	ctxConsumer, err := prepareContextConsumer[Body, MessageAttributes](ctx, queueUrl, message, opt)
 	if err != nil {
-		loggerErr(opt.DebugMode, "error prepare context to consumer:", err)
+		job.reportPoisonMessage(queueUrl, message, err)
 		return
 	}
+	stopHeartbeat := job.startHeartbeat(ctx, queueUrl, ctxConsumer.Message.Id, ctxConsumer.Message.ReceiptHandle)
+	defer stopHeartbeat()
+	ctxConsumer.settlement = newMessageSettlement(job, queueUrl, message, stopHeartbeat)
 	signal := make(chan struct{}, 1)
 	channel := channelMessageProcessed{
 		Signal: &signal,
 	}
-	go processHandler(ctxConsumer, handler, opt, &channel)
+	messageHandler := chainMiddlewares(job.middlewares, func(ctxHandler context.Context, _ string, _ types.Message) error {
+		ctxConsumer.Context = ctxHandler
+		return handler(ctxConsumer)
+	})
+	go processHandler(ctx, func() error {
+		return messageHandler(ctx, queueUrl, message)
+	}, &channel)
 	select {
 	case <-ctx.Done():
 		appendMessagesByResult(ctxConsumer.Message.Id, ctx.Err(), &mgsS, &mgsF)
 		break
 	case <-*channel.Signal:
+		if ctxConsumer.settlement.markSettled() {
+			stopHeartbeat()
+			job.settleMessage(queueUrl, message, channel.Err)
+		}
 		appendMessagesByResult(*message.MessageId, channel.Err, &mgsS, &mgsF)
 		break
 	}
 	return mgsS, mgsF
 }
 
-func processHandler[Body, MessageAttributes any](
-	ctx *Context[Body, MessageAttributes],
-	handler HandlerConsumerFunc[Body, MessageAttributes],
-	opt *option.Consumer,
-	channel *channelMessageProcessed,
-) {
-	err := handler(ctx)
+func processHandler(ctx context.Context, handler func() error, channel *channelMessageProcessed) {
+	err := handler()
 	if ctx.Err() != nil {
 		return
 	}
-	if err == nil && opt.DeleteMessageProcessedSuccess {
-		go deleteMessage(ctx.QueueUrl, ctx.Message.ReceiptHandle)
-	}
 	channel.Err = err
 	*channel.Signal <- struct{}{}
 }
@@ -340,22 +686,34 @@ func prepareContextConsumer[Body, MessageAttributes any](
 	ctx context.Context,
 	queueUrl string,
 	message types.Message,
+	opt *option.Consumer,
 ) (*Context[Body, MessageAttributes], error) {
-	ctxConsumer := &Context[Body, MessageAttributes]{
+	messageReceived, err := prepareMessageReceived[Body, MessageAttributes](message, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &Context[Body, MessageAttributes]{
 		Context:  ctx,
 		QueueUrl: queueUrl,
-	}
+		Message:  messageReceived,
+	}, nil
+}
+
+// prepareMessageReceived converts the message received from AWS SQS to MessageReceived, decoding the body and the
+// message attributes.
+func prepareMessageReceived[Body, MessageAttributes any](
+	message types.Message,
+	opt *option.Consumer,
+) (MessageReceived[Body, MessageAttributes], error) {
 	messageReceived := MessageReceived[Body, MessageAttributes]{
 		Id:                     *message.MessageId,
 		ReceiptHandle:          *message.ReceiptHandle,
 		MD5OfBody:              *message.MD5OfBody,
 		MD5OfMessageAttributes: message.MD5OfMessageAttributes,
 	}
-	var body Body
-	bodyString := *message.Body
-	util.ParseStringToGeneric(bodyString, &body)
-	if util.IsZeroReflect(reflect.ValueOf(body)) {
-		return nil, ErrParseBody
+	body, err := decodeBody[Body](message, opt)
+	if err != nil {
+		return messageReceived, err
 	}
 	messageReceived.Body = body
 	if message.MessageAttributes != nil {
@@ -365,14 +723,61 @@ func prepareContextConsumer[Body, MessageAttributes any](
 			if util.IsMapMessageAttributeValues(messagesAttributes) {
 				messageReceived.MessageAttributes = any(message.MessageAttributes).(MessageAttributes)
 			} else {
-				convertMessageAttributes[MessageAttributes](message.MessageAttributes, &messagesAttributes)
+				err := convertMessageAttributes[MessageAttributes](message.MessageAttributes, &messagesAttributes)
+				if err != nil {
+					return messageReceived, err
+				}
 				messageReceived.MessageAttributes = messagesAttributes
 			}
 		}
 	}
 	fillAttributes[Body, MessageAttributes](message, &messageReceived)
-	ctxConsumer.Message = messageReceived
-	return ctxConsumer, nil
+	return messageReceived, nil
+}
+
+// decodeBody converts the message body with the codec of its content type attribute, or with the
+// option.Consumer.Codec, if none of them is available the body is converted by type.
+func decodeBody[Body any](message types.Message, opt *option.Consumer) (Body, error) {
+	var body Body
+	data := aws.ToString(message.Body)
+	bodyCodec := opt.Codec
+	if contentType, ok := message.MessageAttributes[codec.ContentTypeAttributeName]; ok {
+		if c := codec.Get(aws.ToString(contentType.StringValue)); c != nil {
+			bodyCodec = c
+		}
+	}
+	var err error
+	if opt.StrictBodyDecoding && (bodyCodec == nil || bodyCodec == codec.JSON) {
+		err = decodeBodyStrict(data, &body, bodyCodec == nil, opt.DisallowUnknownFields)
+	} else if bodyCodec != nil {
+		err = bodyCodec.Unmarshal(data, &body)
+	} else {
+		util.ParseStringToGeneric(data, &body)
+		if util.IsZeroReflect(reflect.ValueOf(body)) {
+			return body, ErrParseBody
+		}
+	}
+	if err != nil {
+		return body, fmt.Errorf("%w: %w", ErrParseBody, err)
+	}
+	return body, nil
+}
+
+func decodeBodyStrict[Body any](data string, body *Body, rawString, disallowUnknownFields bool) error {
+	if s, ok := any(body).(*string); ok && rawString {
+		*s = data
+		return nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	if disallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(body); err != nil {
+		return err
+	} else if decoder.More() {
+		return errors.New("invalid data after top-level value")
+	}
+	return nil
 }
 
 func appendMessagesByResult(messageId string, err error, mgsS, mgsF *[]string) {
@@ -383,17 +788,76 @@ func appendMessagesByResult(messageId string, err error, mgsS, mgsF *[]string) {
 	}
 }
 
-func convertMessageAttributes[T any](messageAttributes map[string]types.MessageAttributeValue, dest *T) {
+func convertMessageAttributes[T any](messageAttributes map[string]types.MessageAttributeValue, dest *T) error {
 	m := map[string]any{}
 	for k, v := range messageAttributes {
+		if util.GetBaseDataType(aws.ToString(v.DataType)) == "Binary" {
+			if len(v.BinaryValue) != 0 {
+				m[k] = v.BinaryValue
+			}
+			continue
+		} else if v.StringValue == nil {
+			continue
+		}
 		var valueProcessed any
-		valueString := v.StringValue
-		util.ParseStringToGeneric(*valueString, &valueProcessed)
+		util.ParseStringToGeneric(*v.StringValue, &valueProcessed)
 		if valueProcessed != nil {
 			m[k] = valueProcessed
 		}
 	}
+	err := renameMessageAttributesBySqsTag(reflect.TypeOf(dest).Elem(), messageAttributes, m)
+	if err != nil {
+		return err
+	}
 	_ = util.ParseMapToStruct[T](m, dest)
+	return nil
+}
+
+// renameMessageAttributesBySqsTag moves the values of the attributes named in the sqs tags of t to the json name of
+// the fields, so that they are filled by util.ParseMapToStruct.
+func renameMessageAttributesBySqsTag(
+	t reflect.Type,
+	messageAttributes map[string]types.MessageAttributeValue,
+	m map[string]any,
+) error {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		fieldStruct := t.Field(i)
+		tagValue, ok := fieldStruct.Tag.Lookup("sqs")
+		if !ok || !fieldStruct.IsExported() {
+			continue
+		}
+		tag := util.GetSqsTag(tagValue)
+		if tag.Name == "-" {
+			continue
+		} else if len(tag.Name) == 0 {
+			tag.Name = fieldStruct.Name
+		}
+		fieldName := util.GetJsonNameByTag(fieldStruct.Tag.Get("json"))
+		if len(fieldName) == 0 || fieldName == "-" {
+			fieldName = fieldStruct.Name
+		}
+		value, ok := m[tag.Name]
+		delete(m, tag.Name)
+		if !ok {
+			if tag.Required {
+				return fmt.Errorf("%w: %s", ErrMessageAttributeRequired, tag.Name)
+			}
+			continue
+		}
+		if fieldStruct.Type.Kind() == reflect.String {
+			messageAttValue := messageAttributes[tag.Name]
+			if messageAttValue.StringValue != nil {
+				value = *messageAttValue.StringValue
+			} else {
+				value = string(messageAttValue.BinaryValue)
+			}
+		}
+		m[fieldName] = value
+	}
+	return nil
 }
 
 func fillAttributes[Body, MessageAttributes any](
@@ -434,11 +898,388 @@ func parseContextToSimpleContext[Body any, MessageAttributes map[string]types.Me
 			MD5OfMessageAttributes: ctx.Message.MD5OfMessageAttributes,
 			MessageAttributes:      ctx.Message.MessageAttributes,
 		},
+		settlement: ctx.settlement,
+	}
+}
+
+func newConsumer(ctx context.Context) *Consumer {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Consumer{
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+func (c *Consumer) finish(err error) {
+	c.once.Do(func() {
+		c.err = err
+		c.cancel()
+		close(c.done)
+	})
+}
+
+type consumerJob struct {
+	client         *Client
+	opt            *option.Consumer
+	workers        *workerPool
+	deleter        *deleteBuffer
+	middlewares    []option.Middleware
+	ctxHandlers    context.Context
+	cancelHandlers context.CancelFunc
+}
+
+func newConsumerJob(ctx context.Context, c *Client, queueUrl string, opt *option.Consumer) *consumerJob {
+	ctxHandlers, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	job := &consumerJob{
+		client:         c,
+		opt:            opt,
+		workers:        newWorkerPool(opt.MaxInFlightMessages),
+		ctxHandlers:    ctxHandlers,
+		cancelHandlers: cancelHandlers,
+	}
+	job.middlewares = append([]option.Middleware{job.recoverPanic}, getMiddlewares(opt)...)
+	job.deleter = newDeleteBuffer(c, queueUrl, opt, job.reportError)
+	return job
+}
+
+func (j *consumerJob) deleteMessage(receiptHandle string) error {
+	return j.deleter.add(receiptHandle)
+}
+
+// removeMessage removes the message settled by the consumer, the failure is reported to option.Consumer.OnError.
+func (j *consumerJob) removeMessage(message types.Message) {
+	if err := j.deleteMessage(*message.ReceiptHandle); err != nil {
+		j.reportError(fmt.Errorf("sqs: delete message %s failed: %w", *message.MessageId, err))
+	}
+}
+
+func (j *consumerJob) deleteMessages(receiptHandles []string) {
+	if err := j.deleter.flush(receiptHandles); err != nil {
+		j.reportError(fmt.Errorf("sqs: delete messages failed: %w", err))
+	}
+}
+
+func (j *consumerJob) settleMessage(queueUrl string, message types.Message, err error) {
+	result, err := j.handlerResult(message, err)
+	if err != nil {
+		j.reportError(err)
+		return
+	} else if result == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	switch result.action {
+	case handlerActionRetryAfter:
+		_, err = j.client.ChangeMessageVisibility(ctx, ChangeMessageVisibilityInput{
+			QueueUrl:          queueUrl,
+			ReceiptHandle:     *message.ReceiptHandle,
+			VisibilityTimeout: result.delay,
+		}, &j.opt.Default)
+		if err != nil {
+			j.reportError(fmt.Errorf("sqs: retry message %s after %s failed: %w", *message.MessageId, result.delay, err))
+		}
+	case handlerActionDrop:
+		j.removeMessage(message)
+	case handlerActionDeadLetter:
+		err = j.forwardMessage(ctx, *j.opt.DeadLetterQueueUrl, queueUrl, message, result.reason)
+		if err != nil {
+			j.reportError(fmt.Errorf("sqs: dead letter message %s failed: %w", *message.MessageId, err))
+			return
+		}
+		j.removeMessage(message)
+	}
+}
+
+// handlerResult returns what the consumer does with the message after its handler returned err: nil removes the
+// message if option.Consumer.DeleteMessageProcessedSuccess is set, a *HandlerResult is applied as is, and any other
+// error is retried following option.Consumer.RedeliveryBackoff if it is set. A nil result leaves the message in the
+// queue until the visibility timeout expires, and the error is returned when the result cannot be applied.
+func (j *consumerJob) handlerResult(message types.Message, err error) (*HandlerResult, error) {
+	var result *HandlerResult
+	if err == nil {
+		if !j.opt.DeleteMessageProcessedSuccess {
+			return nil, nil
+		}
+		return &HandlerResult{action: handlerActionDrop}, nil
+	} else if !errors.As(err, &result) {
+		if j.opt.RedeliveryBackoff == nil {
+			return nil, nil
+		}
+		return &HandlerResult{action: handlerActionRetryAfter, delay: j.redeliveryDelay(message)}, nil
+	} else if result.action == handlerActionDeadLetter && j.opt.DeadLetterQueueUrl == nil {
+		return nil, fmt.Errorf("sqs: dead letter message %s failed: %w", *message.MessageId, ErrDeadLetterQueueUrlEmpty)
+	}
+	return result, nil
+}
+
+func (j *consumerJob) redeliveryDelay(message types.Message) time.Duration {
+	receiveCount, _ := strconv.Atoi(message.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	backoff := j.opt.RedeliveryBackoff
+	delay := util.CalculateBackoff(receiveCount, backoff.Initial, backoff.Max, backoff.Multiplier, backoff.Jitter)
+	// the visibility timeout is sent in whole seconds, so the delay is rounded up, otherwise a delay under 1 second
+	// would make the message visible again right away
+	if remainder := delay % time.Second; remainder > 0 {
+		delay += time.Second - remainder
+	}
+	return delay
+}
+
+// forwardMessage sends the message to destQueueUrl with its body and message attributes, adding the reason and the
+// sourceQueueUrl as message attributes while they fit in MaxMessageAttributes, the ones that do not fit are reported
+// to option.Consumer.OnError with ErrForwardAttributesDropped after the message is sent.
+func (j *consumerJob) forwardMessage(
+	ctx context.Context,
+	destQueueUrl,
+	sourceQueueUrl string,
+	message types.Message,
+	reason string,
+) error {
+	messageAttributes := map[string]types.MessageAttributeValue{}
+	for k, v := range message.MessageAttributes {
+		messageAttributes[k] = v
+	}
+	var dropped []string
+	for _, attribute := range []struct{ name, value string }{
+		{FailureReasonAttributeName, reason},
+		{SourceQueueUrlAttributeName, sourceQueueUrl},
+	} {
+		if _, ok := messageAttributes[attribute.name]; !ok && len(messageAttributes) >= MaxMessageAttributes {
+			dropped = append(dropped, fmt.Sprintf("%s=%q", attribute.name, attribute.value))
+			continue
+		}
+		messageAttributes[attribute.name] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(attribute.value),
+		}
+	}
+	input := &sqs.SendMessageInput{
+		MessageBody:       message.Body,
+		QueueUrl:          &destQueueUrl,
+		MessageAttributes: messageAttributes,
+	}
+	if messageGroupId, ok := message.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]; ok {
+		input.MessageGroupId = &messageGroupId
+		input.MessageDeduplicationId = message.MessageId
+	}
+	sqsClient, err := j.client.getApi(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = sqsClient.SendMessage(ctx, input, option.FuncByHttpClient(j.opt.HttpClient))
+	if err == nil && len(dropped) != 0 {
+		j.reportError(fmt.Errorf("sqs: message %s forwarded to %s without %s: %w", *message.MessageId, destQueueUrl,
+			strings.Join(dropped, ", "), ErrForwardAttributesDropped))
+	}
+	return err
+}
+
+// startHeartbeat extends the visibility timeout of the message in the background, if option.Consumer.VisibilityHeartbeat
+// is filled, until ctx is done or the returned function is called. The returned function also waits for the extension
+// in progress, so it's called before settling the message, avoiding that a late extension overrides the settlement.
+func (j *consumerJob) startHeartbeat(ctx context.Context, queueUrl, messageId, receiptHandle string) func() {
+	if j.opt.VisibilityHeartbeat == nil {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		j.extendVisibility(ctx, queueUrl, messageId, receiptHandle)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (j *consumerJob) extendVisibility(ctx context.Context, queueUrl, messageId, receiptHandle string) {
+	heartbeat := j.opt.VisibilityHeartbeat
+	start := time.Now()
+	ticker := time.NewTicker(heartbeat.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		visibilityTimeout := heartbeat.VisibilityTimeout
+		if remaining := heartbeat.MaxExtension - time.Since(start); remaining < time.Second {
+			loggerInfo(j.opt.DebugMode, "Max extension of visibility reached for message:", messageId)
+			return
+		} else if visibilityTimeout > remaining {
+			visibilityTimeout = remaining
+		}
+		ctxChange, cancel := context.WithTimeout(ctx, 5*time.Second)
+		_, err := j.client.ChangeMessageVisibility(ctxChange, ChangeMessageVisibilityInput{
+			QueueUrl:          queueUrl,
+			ReceiptHandle:     receiptHandle,
+			VisibilityTimeout: visibilityTimeout,
+		}, &j.opt.Default)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			j.reportError(fmt.Errorf("sqs: extend visibility of message %s failed: %w", messageId, err))
+		}
+	}
+}
+
+func (j *consumerJob) reportError(err error) {
+	if j.opt.OnError != nil {
+		j.opt.OnError(err)
+	}
+}
+
+// recoverPanic is the outermost middleware of the consumers, the panic of the handler or of the other middlewares is
+// converted to a *PanicError, failing the message like any other error, and reported to option.Consumer.OnError.
+func (j *consumerJob) recoverPanic(next option.MessageHandler) option.MessageHandler {
+	return func(ctx context.Context, queueUrl string, message types.Message) (err error) {
+		defer func() {
+			j.reportPanic(err, aws.ToString(message.MessageId))
+		}()
+		defer recoverPanic(&err)
+		return next(ctx, queueUrl, message)
+	}
+}
+
+func (j *consumerJob) reportPanic(err error, messageIds ...string) {
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		loggerErr(j.opt.DebugMode, "Handler panic on messages", messageIds, ":", panicErr.Value, "\n",
+			string(panicErr.Stack))
+		j.reportError(fmt.Errorf("sqs: handler of messages %v failed: %w", messageIds, err))
+	}
+}
+
+func (j *consumerJob) checkMaxReceiveCount(message types.Message) error {
+	if j.opt.QuarantineQueueUrl == nil || j.opt.MaxReceiveCount <= 0 {
+		return nil
+	}
+	receiveCount, _ := strconv.Atoi(message.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	if receiveCount > j.opt.MaxReceiveCount {
+		return fmt.Errorf("%w: %d of %d", ErrMaxReceiveCountExceeded, receiveCount, j.opt.MaxReceiveCount)
 	}
+	return nil
 }
 
-func deleteMessage(queueUrl, receiptHandle string) {
+func (j *consumerJob) reportPoisonMessage(queueUrl string, message types.Message, err error) {
+	loggerErr(j.opt.DebugMode, "error prepare context to consumer:", err)
+	if j.opt.OnPoisonMessage != nil {
+		j.opt.OnPoisonMessage(queueUrl, message, err)
+	}
+	if j.opt.QuarantineQueueUrl == nil {
+		return
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	_, _ = DeleteMessage(ctx, queueUrl, receiptHandle)
+	errForward := j.forwardMessage(ctx, *j.opt.QuarantineQueueUrl, queueUrl, message, err.Error())
+	if errForward != nil {
+		j.reportError(fmt.Errorf("sqs: quarantine message %s failed: %w", *message.MessageId, errForward))
+		return
+	}
+	loggerInfo(j.opt.DebugMode, "Message", *message.MessageId, "sent to quarantine queue:", *j.opt.QuarantineQueueUrl)
+	j.removeMessage(message)
+}
+
+func (j *consumerJob) shutdown() {
+	loggerInfo(j.opt.DebugMode, "Stopping consumer, waiting for messages in process up to", j.opt.DrainTimeout.String())
+	drained := make(chan struct{})
+	go func() {
+		j.workers.wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(j.opt.DrainTimeout):
+		loggerErr(j.opt.DebugMode, "Drain timeout exceeded, canceling messages in process")
+		j.cancelHandlers()
+		<-drained
+	}
+	j.deleter.close()
+	loggerInfo(j.opt.DebugMode, "Consumer stopped!")
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+type workerPool struct {
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+// workerReservation is a set of workers reserved by a poller before searching for messages, the workers not used are
+// returned to the pool by release.
+type workerReservation struct {
+	pool *workerPool
+	size int
+}
+
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{
+		slots: make(chan struct{}, size),
+	}
+}
+
+// reserve waits for a free worker and reserves up to n of the free workers, it returns nil if ctx is done first.
+func (w *workerPool) reserve(ctx context.Context, n int) *workerReservation {
+	select {
+	case w.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil
+	}
+	reservation := &workerReservation{pool: w, size: 1}
+	for reservation.size < n {
+		select {
+		case w.slots <- struct{}{}:
+			reservation.size++
+		default:
+			return reservation
+		}
+	}
+	return reservation
+}
+
+// run runs f in a worker, waiting for a free one.
+func (w *workerPool) run(f func()) {
+	w.slots <- struct{}{}
+	w.start(f)
+}
+
+func (w *workerPool) start(f func()) {
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.slots
+			w.wg.Done()
+		}()
+		f()
+	}()
+}
+
+func (w *workerPool) wait() {
+	w.wg.Wait()
+}
+
+// run runs f in one of the reserved workers, if all of them are already in use it waits for a free worker of the pool.
+func (r *workerReservation) run(f func()) {
+	if r.size == 0 {
+		r.pool.run(f)
+		return
+	}
+	r.size--
+	r.pool.start(f)
+}
+
+// release returns the workers not used to the pool.
+func (r *workerReservation) release() {
+	for ; r.size > 0; r.size-- {
+		<-r.pool.slots
+	}
 }
@@ -0,0 +1,206 @@
+package sqs
+
+import (
+	"context"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// BatchContext represents the execution context of a batch consumer handler in the package.
+// It contains all messages received by one search in the queue.
+type BatchContext[Body, MessageAttributes any] struct {
+	// context used to process the messages, may have a timeout if you pass the
+	// option.Consumer.ConsumerMessageTimeout as a parameter
+	context.Context `json:"-"`
+	// messages queue url
+	QueueUrl string
+	// converted messages to process
+	Messages []MessageReceived[Body, MessageAttributes]
+}
+
+// HandlerBatchConsumerFunc is a function that consumes a batch of messages and returns the ids of the messages that
+// failed, if an error is returned, all messages in the batch are considered failed.
+type HandlerBatchConsumerFunc[Body, MessageAttributes any] func(ctx *BatchContext[Body, MessageAttributes]) (
+	failedIds []string, err error)
+
+// ReceiveMessageBatch Works as a repeating job like ReceiveMessage, but instead of processing message by message,
+// all messages received by one search in the queue (up to option.Consumer.MaxNumberOfMessages) are converted and passed
+// together to the handler in BatchContext.Messages, useful for bulk operations. The handler returns the ids of the
+// messages that failed, the successful ones are removed from the queue with a single DeleteMessageBatch call, and the
+// failed ones are left in the queue to be received again (see option.Consumer.RedeliveryBackoff).
+//
+// Messages whose body cannot be converted are not passed to the handler and are left in the queue. Each batch occupies
+// one worker of option.Consumer.MaxInFlightMessages.
+//
+// The middlewares registered with Use and in option.Consumer.Middlewares wrap the handler of a single message, so they
+// are not applied to the batch handler, wrap it directly to add cross-cutting behavior. Its panics are still recovered.
+//
+// # Parameters
+//
+// - ctx: context of the job, when it is canceled the consumer stops gracefully (see Consumer.Stop)
+// - queueUrl: url of the queue where you want to fetch messages
+// - handler: function to process the received messages
+// - opts: list of option.Consumer to customize the job
+//
+// # Errors
+//
+// Failures to receive messages from the queue are retried following the option.Consumer.ReceiveErrorPolicy and
+// reported to option.Consumer.OnError, if the policy gives up, the job is stopped returning the error wrapped in
+// ErrReceiveMessageAttemptsExceeded. If the AWS SQS client cannot be created, the job is stopped returning the error.
+//
+// # Returns
+//
+// - error: the error that stopped the job, nil if it was stopped by the ctx or Consumer.Stop
+func ReceiveMessageBatch[Body, MessageAttributes any](
+	ctx context.Context,
+	queueUrl string,
+	handler HandlerBatchConsumerFunc[Body, MessageAttributes],
+	opts ...*option.Consumer,
+) error {
+	return ReceiveMessageBatchWithClient[Body, MessageAttributes](ctx, nil, queueUrl, handler, opts...)
+}
+
+// ReceiveMessageBatchWithClient works like ReceiveMessageBatch, using the client c instead of the default client, if c
+// is nil the default client is used.
+func ReceiveMessageBatchWithClient[Body, MessageAttributes any](
+	ctx context.Context,
+	c *Client,
+	queueUrl string,
+	handler HandlerBatchConsumerFunc[Body, MessageAttributes],
+	opts ...*option.Consumer,
+) error {
+	consumer := newConsumer(ctx)
+	receiveMessageBatch(consumer, c, queueUrl, handler, option.GetConsumerByParams(opts))
+	return consumer.Err()
+}
+
+// ReceiveMessageBatchAsync Works like ReceiveMessageBatch, but the job is processed asynchronously.
+//
+// # Parameters
+//
+// - ctx: context of the job, when it is canceled the consumer stops gracefully (see Consumer.Stop)
+// - queueUrl: url of the queue where you want to fetch messages
+// - handler: function to process the received messages
+// - opts: list of option.Consumer to customize the job
+//
+// # Returns
+//
+// - *Consumer: handle to stop and wait for the job
+func ReceiveMessageBatchAsync[Body, MessageAttributes any](
+	ctx context.Context,
+	queueUrl string,
+	handler HandlerBatchConsumerFunc[Body, MessageAttributes],
+	opts ...*option.Consumer,
+) *Consumer {
+	return ReceiveMessageBatchAsyncWithClient[Body, MessageAttributes](ctx, nil, queueUrl, handler, opts...)
+}
+
+// ReceiveMessageBatchAsyncWithClient works like ReceiveMessageBatchAsync, using the client c instead of the default
+// client, if c is nil the default client is used.
+func ReceiveMessageBatchAsyncWithClient[Body, MessageAttributes any](
+	ctx context.Context,
+	c *Client,
+	queueUrl string,
+	handler HandlerBatchConsumerFunc[Body, MessageAttributes],
+	opts ...*option.Consumer,
+) *Consumer {
+	consumer := newConsumer(ctx)
+	go receiveMessageBatch(consumer, c, queueUrl, handler, option.GetConsumerByParams(opts))
+	return consumer
+}
+
+func receiveMessageBatch[Body, MessageAttributes any](
+	consumer *Consumer,
+	c *Client,
+	queueUrl string,
+	handler HandlerBatchConsumerFunc[Body, MessageAttributes],
+	opt *option.Consumer,
+) {
+	runConsumer(consumer, c, queueUrl, opt, true, func(output *sqs.ReceiveMessageOutput, job *consumerJob,
+		workers *workerReservation) {
+		workers.run(func() {
+			processMessageBatch(queueUrl, handler, output.Messages, job)
+		})
+	})
+}
+
+func processMessageBatch[Body, MessageAttributes any](
+	queueUrl string,
+	handler HandlerBatchConsumerFunc[Body, MessageAttributes],
+	messages []types.Message,
+	job *consumerJob,
+) {
+	opt := job.opt
+	ctx, cancel := context.WithTimeout(job.ctxHandlers, opt.ConsumerMessageTimeout)
+	defer cancel()
+	ctxBatch := &BatchContext[Body, MessageAttributes]{
+		Context:  ctx,
+		QueueUrl: queueUrl,
+	}
+	messagesById := map[string]types.Message{}
+	stopHeartbeats := map[string]func(){}
+	var messageIds []string
+	for _, message := range messages {
+		err := job.checkMaxReceiveCount(message)
+		if err != nil {
+			job.reportPoisonMessage(queueUrl, message, err)
+			continue
+		}
+		ctxConsumer, err := prepareContextConsumer[Body, MessageAttributes](ctx, queueUrl, message, opt)
+		if err != nil {
+			job.reportPoisonMessage(queueUrl, message, err)
+			continue
+		}
+		stopHeartbeat := job.startHeartbeat(ctx, queueUrl, ctxConsumer.Message.Id, ctxConsumer.Message.ReceiptHandle)
+		defer stopHeartbeat()
+		stopHeartbeats[ctxConsumer.Message.Id] = stopHeartbeat
+		messagesById[ctxConsumer.Message.Id] = message
+		messageIds = append(messageIds, ctxConsumer.Message.Id)
+		ctxBatch.Messages = append(ctxBatch.Messages, ctxConsumer.Message)
+	}
+	if len(ctxBatch.Messages) == 0 {
+		return
+	}
+	done := make(chan struct{})
+	var failedIds []string
+	var err error
+	go func() {
+		defer close(done)
+		defer func() {
+			job.reportPanic(err, messageIds...)
+		}()
+		defer recoverPanic(&err)
+		failedIds, err = handler(ctxBatch)
+	}()
+	select {
+	case <-ctx.Done():
+		loggerErr(opt.DebugMode, "error process messages batch:", ctx.Err())
+		return
+	case <-done:
+	}
+	failed := map[string]bool{}
+	for _, id := range failedIds {
+		failed[id] = true
+	}
+	var receiptHandles []string
+	var mgsS, mgsF []string
+	for _, messageReceived := range ctxBatch.Messages {
+		message := messagesById[messageReceived.Id]
+		stopHeartbeats[messageReceived.Id]()
+		if err != nil || failed[messageReceived.Id] {
+			errMessage := err
+			if errMessage == nil {
+				errMessage = ErrMessageBatchFailed
+			}
+			job.settleMessage(queueUrl, message, errMessage)
+			mgsF = append(mgsF, messageReceived.Id)
+			continue
+		}
+		receiptHandles = append(receiptHandles, messageReceived.ReceiptHandle)
+		mgsS = append(mgsS, messageReceived.Id)
+	}
+	job.deleteMessages(receiptHandles)
+	loggerInfo(opt.DebugMode, "Finish process messages batch!", "processed:", len(ctxBatch.Messages), "success:", mgsS,
+		"failed:", mgsF)
+}
@@ -2,6 +2,18 @@ package sqs
 
 import (
 	"context"
+	"errors"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/codec"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/sqstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -9,11 +21,6 @@ import (
 func TestReceiveMessage(t *testing.T) {
 	for _, tt := range initListTestConsumer[test, messageAttTest]() {
 		t.Run(tt.name, func(t *testing.T) {
-			defer func() {
-				if r := recover(); (r != nil) != tt.wantErr {
-					t.Errorf("ReceiveMessage() error = %v, wantErr %v", r, tt.wantErr)
-				}
-			}()
 			initMessageString()
 			initMessageStruct(tt.queueUrl)
 			d := 5 * time.Second
@@ -22,14 +29,14 @@ func TestReceiveMessage(t *testing.T) {
 			}
 			ctx, cancel := context.WithTimeout(context.TODO(), d)
 			defer cancel()
-			ctxInterrupt = ctx
+			var err error
 			if tt.async {
-				ReceiveMessageAsync(tt.queueUrl, tt.handler, tt.opts...)
-				select {
-				case <-ctx.Done():
-				}
+				err = ReceiveMessageAsync(ctx, tt.queueUrl, tt.handler, tt.opts...).Wait()
 			} else {
-				ReceiveMessage(tt.queueUrl, tt.handler, tt.opts...)
+				err = ReceiveMessage(ctx, tt.queueUrl, tt.handler, tt.opts...)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReceiveMessage() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
@@ -38,11 +45,6 @@ func TestReceiveMessage(t *testing.T) {
 func TestSimpleReceiveMessage(t *testing.T) {
 	for _, tt := range initListTestSimpleConsumer[test]() {
 		t.Run(tt.name, func(t *testing.T) {
-			defer func() {
-				if r := recover(); (r != nil) != tt.wantErr {
-					t.Errorf("SimpleReceiveMessage() error = %v, wantErr %v", r, tt.wantErr)
-				}
-			}()
 			initMessageString()
 			initMessageStruct(tt.queueUrl)
 			d := 5 * time.Second
@@ -51,15 +53,763 @@ func TestSimpleReceiveMessage(t *testing.T) {
 			}
 			ctx, cancel := context.WithTimeout(context.TODO(), d)
 			defer cancel()
-			ctxInterrupt = ctx
+			var err error
+			if tt.async {
+				err = SimpleReceiveMessageAsync(ctx, tt.queueUrl, tt.handler, tt.opts...).Wait()
+			} else {
+				err = SimpleReceiveMessage(ctx, tt.queueUrl, tt.handler, tt.opts...)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SimpleReceiveMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReceiveMessageBatch(t *testing.T) {
+	for _, tt := range initListTestBatchConsumer[test, messageAttTest]() {
+		t.Run(tt.name, func(t *testing.T) {
+			initMessageString()
+			initMessageStruct(tt.queueUrl)
+			d := 5 * time.Second
+			if tt.name == "failed" {
+				d = 20 * time.Second
+			}
+			ctx, cancel := context.WithTimeout(context.TODO(), d)
+			defer cancel()
+			var err error
 			if tt.async {
-				SimpleReceiveMessageAsync(tt.queueUrl, tt.handler, tt.opts...)
+				err = ReceiveMessageBatchAsync(ctx, tt.queueUrl, tt.handler, tt.opts...).Wait()
+			} else {
+				err = ReceiveMessageBatch(ctx, tt.queueUrl, tt.handler, tt.opts...)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReceiveMessageBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConsumerStop(t *testing.T) {
+	initMessageStruct(os.Getenv(sqsQueueTestUrl))
+	consumer := SimpleReceiveMessageAsync(context.TODO(), os.Getenv(sqsQueueTestUrl), initSimpleHandleConsumer[test],
+		initOptionsConsumerDefault()...)
+	time.Sleep(2 * time.Second)
+	consumer.Stop()
+	select {
+	case <-consumer.Done():
+	case <-time.After(40 * time.Second):
+		t.Error("Consumer.Stop() timeout exceeded")
+	}
+	if err := consumer.Err(); err != nil {
+		t.Errorf("Consumer.Err() error = %v", err)
+	}
+}
+
+func TestConsumerMaxInFlightMessages(t *testing.T) {
+	fake := sqstest.NewFake()
+	api := &receiveRecorderApi{API: fake}
+	c := NewClientFromAPI(api)
+	queueUrl := fake.NewQueue("in-flight", nil)
+	for i := 0; i < 20; i++ {
+		if _, err := c.SendMessage(context.TODO(), queueUrl, initTestStruct()); err != nil {
+			t.Fatalf("SendMessage() error = %v", err)
+		}
+	}
+	var inFlight, maxInFlight, processed atomic.Int32
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+	err := ReceiveMessageWithClient(ctx, c, queueUrl, func(ctx *Context[test, messageAttTest]) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for m := maxInFlight.Load(); n > m; m = maxInFlight.Load() {
+			if maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		if processed.Add(1) == 20 {
+			cancel()
+		}
+		return nil
+	}, option.NewConsumer().SetMaxInFlightMessages(3).SetPollers(2).SetDelayQueryLoop(100*time.Millisecond))
+	if err != nil {
+		t.Errorf("ReceiveMessageWithClient() error = %v", err)
+	}
+	if processed.Load() != 20 || maxInFlight.Load() > 3 || maxInFlight.Load() < 2 {
+		t.Errorf("ReceiveMessageWithClient() processed = %d, max in flight = %d, want 20 and up to 3",
+			processed.Load(), maxInFlight.Load())
+	}
+	for _, maxNumberOfMessages := range api.maxNumberOfMessages {
+		if maxNumberOfMessages < 1 || maxNumberOfMessages > 3 {
+			t.Errorf("ReceiveMessage() MaxNumberOfMessages = %d, want between 1 and 3", maxNumberOfMessages)
+		}
+	}
+}
+
+func TestConsumerDefaultMaxNumberOfMessages(t *testing.T) {
+	api := &receiveRecorderApi{API: initMockApi()}
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	err := ReceiveMessageWithClient(ctx, NewClientFromAPI(api), "https://sqs.mock/queue",
+		func(ctx *Context[test, messageAttTest]) error {
+			cancel()
+			return nil
+		}, option.NewConsumer().SetDelayQueryLoop(time.Second))
+	if err != nil {
+		t.Errorf("ReceiveMessageWithClient() error = %v", err)
+	}
+	if len(api.maxNumberOfMessages) == 0 || api.maxNumberOfMessages[0] != 10 {
+		t.Errorf("ReceiveMessage() MaxNumberOfMessages = %v, want 10", api.maxNumberOfMessages)
+	}
+}
+
+func TestAckAfterDrainTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	acked := make(chan error, 1)
+	consumer := ReceiveMessageAsyncWithClient(context.TODO(), NewClientFromAPI(initMockApi()), "https://sqs.mock/queue",
+		func(ctx *Context[test, messageAttTest]) error {
+			close(started)
+			<-release
+			acked <- ctx.Ack()
+			return nil
+		}, option.NewConsumer().SetDrainTimeout(100*time.Millisecond).SetDelayQueryLoop(time.Second))
+	<-started
+	consumer.Stop()
+	if err := consumer.Wait(); err != nil {
+		t.Errorf("Consumer.Wait() error = %v", err)
+	}
+	close(release)
+	if err := <-acked; !errors.Is(err, ErrConsumerStopped) {
+		t.Errorf("Ack() error = %v, want %v", err, ErrConsumerStopped)
+	}
+}
+
+func TestVisibilityHeartbeat(t *testing.T) {
+	api := initMockApi()
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	var extensions int
+	err := ReceiveMessageWithClient(ctx, NewClientFromAPI(api), "https://sqs.mock/queue",
+		func(ctx *Context[test, messageAttTest]) error {
+			time.Sleep(2500 * time.Millisecond)
+			extensions = len(api.changedVisibilities())
+			cancel()
+			return nil
+		}, option.NewConsumer().
+			SetDelayQueryLoop(time.Second).
+			SetVisibilityHeartbeat(option.VisibilityHeartbeat{Interval: time.Second}))
+	if err != nil {
+		t.Errorf("ReceiveMessageWithClient() error = %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	visibilities := api.changedVisibilities()
+	if extensions != 2 || len(visibilities) != extensions {
+		t.Errorf("ReceiveMessageWithClient() extensions = %d, after return = %d, want 2", extensions, len(visibilities))
+	}
+	for _, visibility := range visibilities {
+		if aws.ToString(visibility.ReceiptHandle) != "mock-receipt-handle" || visibility.VisibilityTimeout != 2 {
+			t.Errorf("ChangeMessageVisibility() input = %+v, want mock-receipt-handle and 2 seconds", visibility)
+		}
+	}
+}
+
+func TestVisibilityHeartbeatSettle(t *testing.T) {
+	for _, tt := range initListTestVisibilityHeartbeatSettle() {
+		t.Run(tt.name, func(t *testing.T) {
+			api := initMockApi()
+			api.visibilityDelay = 300 * time.Millisecond
+			api.messages = nil
+			for i := 0; i < tt.messages; i++ {
+				api.messages = append(api.messages, types.Message{
+					MessageId:     aws.String("mock-message-id-" + strconv.Itoa(i)),
+					ReceiptHandle: aws.String("mock-receipt-handle-" + strconv.Itoa(i)),
+					MD5OfBody:     aws.String(""),
+					Body:          aws.String(`{"name":"Test Name"}`),
+					Attributes:    map[string]string{"ApproximateReceiveCount": "1"},
+				})
+			}
+			ctx, cancel := context.WithTimeout(context.TODO(), 4*time.Second)
+			defer cancel()
+			err := tt.receive(ctx, NewClientFromAPI(api), option.NewConsumer().
+				SetDelayQueryLoop(time.Second).
+				SetMaxInFlightMessages(tt.messages).
+				SetVisibilityHeartbeat(option.VisibilityHeartbeat{Interval: time.Second}))
+			if err != nil {
+				t.Errorf("receive() error = %v", err)
+			}
+			visibilities := map[string]int32{}
+			for _, visibility := range api.changedVisibilities() {
+				visibilities[aws.ToString(visibility.ReceiptHandle)] = visibility.VisibilityTimeout
+			}
+			for i := 0; i < tt.messages; i++ {
+				receiptHandle := "mock-receipt-handle-" + strconv.Itoa(i)
+				if visibilities[receiptHandle] != tt.wantVisibility {
+					t.Errorf("receive() last visibility of %s = %d, want %d", receiptHandle,
+						visibilities[receiptHandle], tt.wantVisibility)
+				}
+			}
+		})
+	}
+}
+
+func TestContextNotSettleable(t *testing.T) {
+	ctx := &Context[test, messageAttTest]{Context: context.TODO()}
+	if err := ctx.Ack(); !errors.Is(err, ErrMessageNotSettleable) {
+		t.Errorf("Ack() error = %v, want %v", err, ErrMessageNotSettleable)
+	}
+	if err := ctx.Nack(time.Second); !errors.Is(err, ErrMessageNotSettleable) {
+		t.Errorf("Nack() error = %v, want %v", err, ErrMessageNotSettleable)
+	}
+	simpleCtx := &SimpleContext[test]{Context: context.TODO()}
+	if err := simpleCtx.ExtendVisibility(time.Second); !errors.Is(err, ErrMessageNotSettleable) {
+		t.Errorf("ExtendVisibility() error = %v, want %v", err, ErrMessageNotSettleable)
+	}
+}
+
+func TestMessageAttributesConversion(t *testing.T) {
+	opt := option.NewProducer().SetMessageAttributes(initMessageAttTest())
+	input, err := prepareMessageInput("https://sqs.mock/queue", initTestStruct(), option.GetProducerByParams(
+		[]*option.Producer{opt}))
+	if err != nil {
+		t.Fatalf("prepareMessageInput() error = %v", err)
+	}
+	if dataType := aws.ToString(input.MessageAttributes["bytes"].DataType); dataType != "Binary" {
+		t.Errorf("prepareMessageInput() bytes data type = %v, want Binary", dataType)
+	}
+	input.MessageAttributes["custom"] = types.MessageAttributeValue{
+		DataType:    aws.String("Number.float"),
+		StringValue: aws.String("1.5"),
+	}
+	input.MessageAttributes["binaryOnly"] = types.MessageAttributeValue{DataType: aws.String("Binary.gzip")}
+	message := types.Message{
+		MessageId:         aws.String("1"),
+		ReceiptHandle:     aws.String("1"),
+		MD5OfBody:         aws.String(""),
+		Body:              input.MessageBody,
+		MessageAttributes: input.MessageAttributes,
+	}
+	ctx, err := prepareContextConsumer[test, messageAttTest](context.TODO(), "https://sqs.mock/queue", message,
+		option.GetConsumerByParams(nil))
+	if err != nil {
+		t.Fatalf("prepareContextConsumer() error = %v", err)
+	}
+	if string(ctx.Message.MessageAttributes.Bytes) != "bytes test" || ctx.Message.MessageAttributes.Balance != 10.23 {
+		t.Errorf("prepareContextConsumer() message attributes = %+v", ctx.Message.MessageAttributes)
+	}
+	opt = option.NewProducer().SetMessageAttributes(input.MessageAttributes)
+	inputMap, err := prepareMessageInput("https://sqs.mock/queue", "body test", option.GetProducerByParams(
+		[]*option.Producer{opt}))
+	if err != nil {
+		t.Fatalf("prepareMessageInput() error = %v", err)
+	}
+	if dataType := aws.ToString(inputMap.MessageAttributes["custom"].DataType); dataType != "Number.float" {
+		t.Errorf("prepareMessageInput() custom data type = %v, want Number.float", dataType)
+	}
+}
+
+func TestMessageAttributesSqsTag(t *testing.T) {
+	v := initMessageAttTagTest()
+	opt := option.GetProducerByParams([]*option.Producer{option.NewProducer().SetMessageAttributes(v)})
+	input, err := prepareMessageInput("https://sqs.mock/queue", "body test", opt)
+	if err != nil {
+		t.Fatalf("prepareMessageInput() error = %v", err)
+	}
+	wantDataTypes := map[string]string{
+		"userName": "String",
+		"balance":  "Number.float",
+		"count":    "Number",
+		"payload":  "Binary",
+		"code":     "Binary",
+	}
+	if len(input.MessageAttributes) != len(wantDataTypes) {
+		t.Errorf("prepareMessageInput() message attributes = %v, want %v", input.MessageAttributes, wantDataTypes)
+	}
+	for name, dataType := range wantDataTypes {
+		if got := aws.ToString(input.MessageAttributes[name].DataType); got != dataType {
+			t.Errorf("prepareMessageInput() %s data type = %v, want %v", name, got, dataType)
+		}
+	}
+	message := types.Message{
+		MessageId:         aws.String("1"),
+		ReceiptHandle:     aws.String("1"),
+		MD5OfBody:         aws.String(""),
+		Body:              input.MessageBody,
+		MessageAttributes: input.MessageAttributes,
+	}
+	ctx, err := prepareContextConsumer[string, messageAttTagTest](context.TODO(), "https://sqs.mock/queue", message,
+		option.GetConsumerByParams(nil))
+	if err != nil {
+		t.Fatalf("prepareContextConsumer() error = %v", err)
+	}
+	v.Hidden = ""
+	got := ctx.Message.MessageAttributes
+	if got.Name != v.Name || got.Balance != v.Balance || got.Code != v.Code || string(got.Payload) != string(v.Payload) {
+		t.Errorf("prepareContextConsumer() message attributes = %+v, want %+v", got, v)
+	}
+	delete(message.MessageAttributes, "userName")
+	_, err = prepareContextConsumer[string, messageAttTagTest](context.TODO(), "https://sqs.mock/queue", message,
+		option.GetConsumerByParams(nil))
+	if !errors.Is(err, ErrMessageAttributeRequired) {
+		t.Errorf("prepareContextConsumer() error = %v, want %v", err, ErrMessageAttributeRequired)
+	}
+	v.Name = ""
+	opt = option.GetProducerByParams([]*option.Producer{option.NewProducer().SetMessageAttributes(v)})
+	_, err = prepareMessageInput("https://sqs.mock/queue", "body test", opt)
+	if !errors.Is(err, ErrMessageAttributeRequired) {
+		t.Errorf("prepareMessageInput() error = %v, want %v", err, ErrMessageAttributeRequired)
+	}
+}
+
+func TestMessageBodyCodec(t *testing.T) {
+	v := test{Name: "Test Name", Emails: []string{"test@gmail.com"}, Bank: bank{Account: "123456"}}
+	for _, c := range []codec.Codec{codec.JSON, codec.Msgpack} {
+		opt := option.GetProducerByParams([]*option.Producer{option.NewProducer().SetCodec(c)})
+		input, err := prepareMessageInput("https://sqs.mock/queue", v, opt)
+		if err != nil {
+			t.Fatalf("prepareMessageInput() error = %v", err)
+		}
+		contentType := input.MessageAttributes[codec.ContentTypeAttributeName]
+		if aws.ToString(contentType.StringValue) != c.ContentType() {
+			t.Errorf("prepareMessageInput() content type = %v, want %v", aws.ToString(contentType.StringValue),
+				c.ContentType())
+		}
+		message := types.Message{
+			MessageId:         aws.String("1"),
+			ReceiptHandle:     aws.String("1"),
+			MD5OfBody:         aws.String(""),
+			Body:              input.MessageBody,
+			MessageAttributes: input.MessageAttributes,
+		}
+		ctx, err := prepareContextConsumer[test, map[string]types.MessageAttributeValue](context.TODO(),
+			"https://sqs.mock/queue", message, option.GetConsumerByParams(nil))
+		if err != nil {
+			t.Fatalf("prepareContextConsumer() error = %v", err)
+		}
+		if ctx.Message.Body.Name != v.Name || ctx.Message.Body.Bank != v.Bank {
+			t.Errorf("prepareContextConsumer() body = %+v, want %+v", ctx.Message.Body, v)
+		}
+	}
+	message := types.Message{
+		MessageId:     aws.String("1"),
+		ReceiptHandle: aws.String("1"),
+		MD5OfBody:     aws.String(""),
+		Body:          aws.String("invalid"),
+	}
+	opt := option.GetConsumerByParams([]*option.Consumer{option.NewConsumer().SetCodec(codec.Msgpack)})
+	_, err := prepareContextConsumer[test, messageAttTest](context.TODO(), "https://sqs.mock/queue", message, opt)
+	if !errors.Is(err, ErrParseBody) {
+		t.Errorf("prepareContextConsumer() error = %v, want %v", err, ErrParseBody)
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	for _, tt := range initListTestDecodeBody() {
+		t.Run(tt.name, func(t *testing.T) {
+			message := types.Message{Body: aws.String(tt.body)}
+			_, err := decodeBody[test](message, option.GetConsumerByParams([]*option.Consumer{tt.opt}))
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Errorf("decodeBody() error = %v, wantErr false", err)
+			} else if len(tt.wantErr) != 0 && (err == nil || !strings.Contains(err.Error(), tt.wantErr) ||
+				!errors.Is(err, ErrParseBody)) {
+				t.Errorf("decodeBody() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	opt := option.GetConsumerByParams([]*option.Consumer{option.NewConsumer().SetStrictBodyDecoding(true)})
+	if body, err := decodeBody[int](types.Message{Body: aws.String("0")}, opt); err != nil || body != 0 {
+		t.Errorf("decodeBody() = %v, %v, want 0", body, err)
+	}
+	if body, err := decodeBody[string](types.Message{Body: aws.String("body test")}, opt); err != nil ||
+		body != "body test" {
+		t.Errorf("decodeBody() = %v, %v, want body test", body, err)
+	}
+}
+
+func TestOnPoisonMessage(t *testing.T) {
+	api := initMockApi()
+	api.messages[0].Body = aws.String(`{"name":1}`)
+	var poisonErr error
+	ctx, cancel := context.WithTimeout(context.TODO(), 3*time.Second)
+	defer cancel()
+	err := ReceiveMessageWithClient(ctx, NewClientFromAPI(api), "https://sqs.mock/queue",
+		initHandleConsumer[test, messageAttTest], option.NewConsumer().
+			SetStrictBodyDecoding(true).
+			SetDelayQueryLoop(time.Second).
+			SetOnPoisonMessage(func(queueUrl string, message types.Message, err error) {
+				poisonErr = err
+				cancel()
+			}))
+	if err != nil {
+		t.Errorf("ReceiveMessageWithClient() error = %v", err)
+	}
+	if !errors.Is(poisonErr, ErrParseBody) || !strings.Contains(poisonErr.Error(), "test.name") {
+		t.Errorf("OnPoisonMessage() error = %v, want %v", poisonErr, ErrParseBody)
+	}
+}
+
+func TestQuarantineMessage(t *testing.T) {
+	for _, tt := range initListTestQuarantineMessage() {
+		t.Run(tt.name, func(t *testing.T) {
+			api := initMockApi()
+			api.messages[0].Body = aws.String(tt.body)
+			api.messages[0].Attributes = map[string]string{"ApproximateReceiveCount": tt.receiveCount}
+			api.messages[0].MessageAttributes = map[string]types.MessageAttributeValue{}
+			for i := 0; i < tt.messageAttributes; i++ {
+				api.messages[0].MessageAttributes["attribute"+strconv.Itoa(i)] = types.MessageAttributeValue{
+					DataType:    aws.String("String"),
+					StringValue: aws.String("value"),
+				}
+			}
+			reported := make(chan error, 1)
+			ctx, cancel := context.WithTimeout(context.TODO(), 2*time.Second)
+			defer cancel()
+			err := ReceiveMessageWithClient(ctx, NewClientFromAPI(api), "https://sqs.mock/queue",
+				initHandleConsumer[test, messageAttTest], option.NewConsumer().
+					SetStrictBodyDecoding(true).
+					SetDelayQueryLoop(time.Second).
+					SetQuarantineQueueUrl("https://sqs.mock/quarantine").
+					SetMaxReceiveCount(3).
+					SetOnError(func(err error) {
+						select {
+						case reported <- err:
+						default:
+						}
+					}))
+			if err != nil {
+				t.Errorf("ReceiveMessageWithClient() error = %v", err)
+			}
+			if len(api.sendInputs) != 1 {
+				t.Fatalf("ReceiveMessageWithClient() quarantined = %d, want 1", len(api.sendInputs))
+			}
+			input := api.sendInputs[0]
+			failureReason := aws.ToString(input.MessageAttributes[FailureReasonAttributeName].StringValue)
+			sourceQueueUrl := aws.ToString(input.MessageAttributes[SourceQueueUrlAttributeName].StringValue)
+			if tt.wantDropped {
 				select {
-				case <-ctx.Done():
+				case err = <-reported:
+				default:
 				}
-			} else {
-				SimpleReceiveMessage(tt.queueUrl, tt.handler, tt.opts...)
+				if !errors.Is(err, ErrForwardAttributesDropped) {
+					t.Fatalf("OnError() err = %v, want %v", err, ErrForwardAttributesDropped)
+				}
+				failureReason = err.Error()
+				if strings.Contains(failureReason, "https://sqs.mock/queue") {
+					sourceQueueUrl = "https://sqs.mock/queue"
+				}
+			}
+			if aws.ToString(input.QueueUrl) != "https://sqs.mock/quarantine" ||
+				aws.ToString(input.MessageBody) != tt.body || !strings.Contains(failureReason, tt.wantErr.Error()) ||
+				sourceQueueUrl != "https://sqs.mock/queue" {
+				t.Errorf("ReceiveMessageWithClient() quarantined input = %+v, reason = %s", input, failureReason)
+			}
+			if receiptHandles := api.deletedReceiptHandles(); len(receiptHandles) != 1 {
+				t.Errorf("ReceiveMessageWithClient() deleted = %v, want mock-receipt-handle", receiptHandles)
 			}
 		})
 	}
 }
+
+func TestHandlerResult(t *testing.T) {
+	for _, tt := range initListTestHandlerResult() {
+		t.Run(tt.name, func(t *testing.T) {
+			api := initMockApi()
+			api.messages[0].Attributes = map[string]string{"ApproximateReceiveCount": tt.receiveCount}
+			api.messages[0].MessageAttributes = map[string]types.MessageAttributeValue{}
+			for i := 0; i < tt.messageAttributes; i++ {
+				api.messages[0].MessageAttributes["attribute"+strconv.Itoa(i)] = types.MessageAttributeValue{
+					DataType:    aws.String("String"),
+					StringValue: aws.String("value"),
+				}
+			}
+			var mutex sync.Mutex
+			var reported []error
+			ctx, cancel := context.WithTimeout(context.TODO(), 1500*time.Millisecond)
+			defer cancel()
+			err := ReceiveMessageWithClient(ctx, NewClientFromAPI(api), "https://sqs.mock/queue", tt.handler,
+				option.NewConsumer().SetDelayQueryLoop(time.Second).SetOnError(func(err error) {
+					mutex.Lock()
+					defer mutex.Unlock()
+					reported = append(reported, err)
+				}), tt.opt)
+			if err != nil {
+				t.Errorf("ReceiveMessageWithClient() error = %v", err)
+			}
+			mutex.Lock()
+			if (tt.wantErr == nil && len(reported) != 0) || (tt.wantErr != nil &&
+				(len(reported) != 1 || !errors.Is(reported[0], tt.wantErr))) {
+				t.Errorf("OnError() errors = %v, want %v", reported, tt.wantErr)
+			}
+			mutex.Unlock()
+			if receiptHandles := api.deletedReceiptHandles(); (len(receiptHandles) == 1) != tt.wantDeleted {
+				t.Errorf("ReceiveMessageWithClient() deleted = %v, wantDeleted %v", receiptHandles, tt.wantDeleted)
+			}
+			var visibilities []int32
+			for _, visibility := range api.changedVisibilities() {
+				visibilities = append(visibilities, visibility.VisibilityTimeout)
+			}
+			if !reflect.DeepEqual(visibilities, tt.wantVisibilities) {
+				t.Errorf("ReceiveMessageWithClient() visibilities = %v, want %v", visibilities, tt.wantVisibilities)
+			}
+			if !tt.wantForwarded {
+				if len(api.sendInputs) != 0 {
+					t.Errorf("ReceiveMessageWithClient() forwarded = %d, want none", len(api.sendInputs))
+				}
+				return
+			} else if len(api.sendInputs) != 1 {
+				t.Fatalf("ReceiveMessageWithClient() forwarded = %d, want 1", len(api.sendInputs))
+			}
+			input := api.sendInputs[0]
+			if aws.ToString(input.QueueUrl) != "https://sqs.mock/dlq" ||
+				aws.ToString(input.MessageBody) != aws.ToString(initMockApi().messages[0].Body) ||
+				len(input.MessageAttributes) != tt.messageAttributes+len(tt.wantMetadata) {
+				t.Errorf("ReceiveMessageWithClient() forwarded input = %+v", input)
+			}
+			wantValues := map[string]string{
+				FailureReasonAttributeName:  "test dead letter",
+				SourceQueueUrlAttributeName: "https://sqs.mock/queue",
+			}
+			for _, name := range tt.wantMetadata {
+				if value := aws.ToString(input.MessageAttributes[name].StringValue); value != wantValues[name] {
+					t.Errorf("ReceiveMessageWithClient() forwarded %s = %s, want %s", name, value, wantValues[name])
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteBuffer(t *testing.T) {
+	for _, tt := range initListTestDeleteBuffer() {
+		t.Run(tt.name, func(t *testing.T) {
+			api := initMockApi()
+			api.deleteFailures = map[string]int{"mock-receipt-handle": tt.failures}
+			api.senderFault = tt.senderFault
+			api.deleteBatchErr = tt.err
+			var reported []error
+			opt := option.GetConsumerByParams([]*option.Consumer{option.NewConsumer().SetDeleteBuffer(option.DeleteBuffer{
+				MaxAttempts: tt.maxAttempts,
+				Backoff:     option.Backoff{Initial: 10 * time.Millisecond},
+			})})
+			buffer := newDeleteBuffer(NewClientFromAPI(api), "https://sqs.mock/queue", opt, func(err error) {
+				reported = append(reported, err)
+			})
+			if err := buffer.add("mock-receipt-handle"); err != nil {
+				t.Errorf("add() error = %v", err)
+			}
+			buffer.close()
+			if receiptHandles := api.deletedReceiptHandles(); (len(receiptHandles) == 1) != tt.wantDeleted {
+				t.Errorf("close() deleted = %v, wantDeleted %v", receiptHandles, tt.wantDeleted)
+			}
+			var deleteErr *DeleteMessageError
+			if tt.wantErrAttempt == 0 {
+				if len(reported) != 0 {
+					t.Errorf("reportError() errors = %v, want none", reported)
+				}
+			} else if len(reported) != 1 || !errors.As(reported[0], &deleteErr) ||
+				deleteErr.Attempt != tt.wantErrAttempt || deleteErr.ReceiptHandle != "mock-receipt-handle" {
+				t.Errorf("reportError() errors = %v, want *DeleteMessageError on attempt %d", reported,
+					tt.wantErrAttempt)
+			}
+			if err := buffer.add("mock-receipt-handle"); !errors.Is(err, ErrConsumerStopped) {
+				t.Errorf("add() after close error = %v, want %v", err, ErrConsumerStopped)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var globalCalls atomic.Int32
+	Use(func(next option.MessageHandler) option.MessageHandler {
+		return func(ctx context.Context, queueUrl string, message types.Message) error {
+			globalCalls.Add(1)
+			return next(ctx, queueUrl, message)
+		}
+	})
+	for _, tt := range initListTestMiddleware() {
+		t.Run(tt.name, func(t *testing.T) {
+			var observedErr error
+			observed := false
+			timing := TimingMiddleware(func(queueUrl string, message types.Message, d time.Duration, err error) {
+				observed = true
+				observedErr = err
+			})
+			ctx, cancel := context.WithTimeout(context.TODO(), 2*time.Second)
+			defer cancel()
+			err := ReceiveMessageWithClient(ctx, NewClientFromAPI(initMockApi()), "https://sqs.mock/queue", tt.handler,
+				option.NewConsumer().
+					SetDelayQueryLoop(time.Second).
+					SetMiddlewares(timing, RecoveryMiddleware(), initMiddlewareValue))
+			if err != nil {
+				t.Errorf("ReceiveMessageWithClient() error = %v", err)
+			}
+			var panicErr *PanicError
+			if !observed || (observedErr != nil) != tt.wantErr || (tt.wantErr && !errors.As(observedErr, &panicErr)) {
+				t.Errorf("TimingMiddleware() observed = %v, err = %v, wantErr %v", observed, observedErr, tt.wantErr)
+			}
+		})
+	}
+	if globalCalls.Load() == 0 {
+		t.Error("Use() global middleware not called")
+	}
+}
+
+func TestMiddlewareBatch(t *testing.T) {
+	var calls atomic.Int32
+	counter := func(next option.MessageHandler) option.MessageHandler {
+		return func(ctx context.Context, queueUrl string, message types.Message) error {
+			calls.Add(1)
+			return next(ctx, queueUrl, message)
+		}
+	}
+	Use(counter)
+	ctx, cancel := context.WithTimeout(context.TODO(), 2*time.Second)
+	defer cancel()
+	var handled atomic.Bool
+	err := ReceiveMessageBatchWithClient(ctx, NewClientFromAPI(initMockApi()), "https://sqs.mock/queue",
+		func(ctx *BatchContext[test, messageAttTest]) ([]string, error) {
+			handled.Store(true)
+			cancel()
+			return nil, nil
+		}, option.NewConsumer().SetDelayQueryLoop(time.Second).SetMiddlewares(counter))
+	if err != nil {
+		t.Errorf("ReceiveMessageBatchWithClient() error = %v", err)
+	}
+	if !handled.Load() || calls.Load() != 0 {
+		t.Errorf("ReceiveMessageBatchWithClient() handled = %v, middleware calls = %d, want true and 0",
+			handled.Load(), calls.Load())
+	}
+}
+
+func TestHandlerPanic(t *testing.T) {
+	for _, tt := range initListTestHandlerPanic() {
+		t.Run(tt.name, func(t *testing.T) {
+			api := initMockApi()
+			reported := make(chan error, 1)
+			ctx, cancel := context.WithTimeout(context.TODO(), 2*time.Second)
+			defer cancel()
+			err := tt.receive(ctx, NewClientFromAPI(api), option.NewConsumer().
+				SetDelayQueryLoop(time.Second).
+				SetOnError(func(err error) {
+					select {
+					case reported <- err:
+					default:
+					}
+				}))
+			if err != nil {
+				t.Errorf("receive() error = %v", err)
+			}
+			var panicErr *PanicError
+			select {
+			case err = <-reported:
+				if !errors.As(err, &panicErr) || len(panicErr.Stack) == 0 || strings.Contains(err.Error(), "\n") {
+					t.Errorf("OnError() err = %v, want *PanicError with stack and single line message", err)
+				}
+			default:
+				t.Error("OnError() not called")
+			}
+			if receiptHandles := api.deletedReceiptHandles(); len(receiptHandles) != 0 {
+				t.Errorf("receive() deleted = %v, want none", receiptHandles)
+			}
+		})
+	}
+}
+
+func TestHandlerDriver(t *testing.T) {
+	for _, tt := range initListTestHandlerDriver() {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := NewHandlerDriver(tt.handler, tt.opt)
+			if tt.receiveCount > 0 {
+				driver.SetReceiveCount(tt.receiveCount)
+			}
+			result, err := driver.Run(context.TODO(), "https://sqs.mock/queue", initTestStruct(),
+				option.NewProducer().SetMessageAttributes(initMessageAttTest()))
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if result.Outcome != tt.wantOutcome || result.Delay != tt.wantDelay || (result.Err != nil) != tt.wantErr {
+				t.Errorf("Run() outcome = %s, delay = %s, err = %v, want %s, %s, err %v", result.Outcome,
+					result.Delay, result.Err, tt.wantOutcome, tt.wantDelay, tt.wantErr)
+			}
+			if result.Outcome == HandlerOutcomeQuarantine {
+				if result.Context != nil || !errors.Is(result.Err, ErrMaxReceiveCountExceeded) {
+					t.Errorf("Run() context = %v, err = %v, want nil and %v", result.Context, result.Err,
+						ErrMaxReceiveCountExceeded)
+				}
+				return
+			}
+			if result.Context.Message.Body.Name != initTestStruct().Name ||
+				result.Context.Message.MessageAttributes.Name != initMessageAttTest().Name {
+				t.Errorf("Run() message = %+v, want converted body and message attributes", result.Context.Message)
+			}
+		})
+	}
+}
+
+func TestHandlerDriverConversion(t *testing.T) {
+	result, err := NewSimpleHandlerDriver(initSimpleHandleConsumer[string],
+		option.NewConsumer().SetDeleteMessageProcessedSuccess(true)).
+		Run(context.TODO(), "https://sqs.mock/queue", "body test")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	} else if result.Outcome != HandlerOutcomeAck || result.Context.Message.Body != "body test" {
+		t.Errorf("Run() outcome = %s, body = %s, want ack", result.Outcome, result.Context.Message.Body)
+	}
+	_, err = NewHandlerDriver(initHandleConsumer[test, messageAttTest]).
+		Run(context.TODO(), "https://sqs.mock/queue", `{"name":1}`)
+	if !errors.Is(err, ErrParseBody) {
+		t.Errorf("Run() error = %v, want %v", err, ErrParseBody)
+	}
+}
+
+func TestReceive(t *testing.T) {
+	for _, tt := range initListTestReceive() {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			fake := sqstest.NewFake()
+			c := NewClientFromAPI(fake)
+			queueUrl := fake.NewQueue("receive", nil)
+			for _, body := range tt.bodies {
+				_, err := c.SendMessage(ctx, queueUrl, body,
+					option.NewProducer().SetMessageAttributes(initMessageAttTest()))
+				if err != nil {
+					t.Fatalf("SendMessage() error = %v", err)
+				}
+			}
+			output, err := ReceiveWithClient[test, messageAttTest](ctx, c, queueUrl, tt.opt)
+			if err != nil {
+				t.Fatalf("ReceiveWithClient() error = %v", err)
+			} else if len(output.Messages) != tt.wantSuccess || len(output.Failed) != tt.wantFailed {
+				t.Fatalf("ReceiveWithClient() messages = %d, failed = %d, want %d and %d", len(output.Messages),
+					len(output.Failed), tt.wantSuccess, tt.wantFailed)
+			}
+			for _, failed := range output.Failed {
+				if !errors.Is(failed, ErrParseBody) || failed.QueueUrl != queueUrl {
+					t.Errorf("ReceiveWithClient() failed = %v, want %v", failed, ErrParseBody)
+				}
+			}
+			for _, message := range output.Messages {
+				if message.Body.Name != initTestStruct().Name ||
+					message.MessageAttributes.Name != initMessageAttTest().Name {
+					t.Errorf("ReceiveWithClient() message = %+v, want converted body and message attributes", message)
+				}
+				_, err = c.DeleteMessage(ctx, queueUrl, message.ReceiptHandle)
+				if err != nil {
+					t.Errorf("DeleteMessage() error = %v", err)
+				}
+			}
+			if messages := fake.Messages(queueUrl); len(messages) != len(tt.bodies)-tt.wantSuccess {
+				t.Errorf("Messages() = %d, want %d left in the queue", len(messages), len(tt.bodies)-tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestReceiveQueueDoesNotExist(t *testing.T) {
+	c := NewClientFromAPI(sqstest.NewFake())
+	_, err := SimpleReceiveWithClient[string](context.TODO(), c, "https://sqs.us-east-1.amazonaws.com/000000000000/none")
+	var queueErr *types.QueueDoesNotExist
+	if !errors.As(err, &queueErr) {
+		t.Errorf("SimpleReceiveWithClient() error = %v, want *types.QueueDoesNotExist", err)
+	}
+}
@@ -0,0 +1,185 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/util"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type deleteBuffer struct {
+	client        *Client
+	queueUrl      string
+	opt           *option.Consumer
+	reportError   func(err error)
+	receiptHandle chan string
+	done          chan struct{}
+	sending       sync.WaitGroup
+	mutex         sync.RWMutex
+	closed        bool
+}
+
+func newDeleteBuffer(c *Client, queueUrl string, opt *option.Consumer, reportError func(err error)) *deleteBuffer {
+	b := &deleteBuffer{
+		client:        c,
+		queueUrl:      queueUrl,
+		opt:           opt,
+		reportError:   reportError,
+		receiptHandle: make(chan string, opt.DeleteBuffer.Size),
+		done:          make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// add puts the message in the buffer to be removed, it returns ErrConsumerStopped if the buffer is already closed,
+// which happens when a handler settles its message after the consumer stopped.
+func (b *deleteBuffer) add(receiptHandle string) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if b.closed {
+		return ErrConsumerStopped
+	}
+	b.receiptHandle <- receiptHandle
+	return nil
+}
+
+// flush removes the messages without waiting for the buffer, same errors as add.
+func (b *deleteBuffer) flush(receiptHandles []string) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if b.closed {
+		return ErrConsumerStopped
+	}
+	b.dispatch(receiptHandles)
+	return nil
+}
+
+func (b *deleteBuffer) close() {
+	b.mutex.Lock()
+	b.closed = true
+	close(b.receiptHandle)
+	b.mutex.Unlock()
+	<-b.done
+	b.sending.Wait()
+}
+
+func (b *deleteBuffer) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.opt.DeleteBuffer.FlushInterval)
+	defer ticker.Stop()
+	var receiptHandles []string
+	for {
+		select {
+		case receiptHandle, ok := <-b.receiptHandle:
+			if !ok {
+				b.dispatch(receiptHandles)
+				return
+			}
+			receiptHandles = append(receiptHandles, receiptHandle)
+			if len(receiptHandles) < b.opt.DeleteBuffer.Size {
+				continue
+			}
+		case <-ticker.C:
+		}
+		b.dispatch(receiptHandles)
+		receiptHandles = nil
+	}
+}
+
+func (b *deleteBuffer) dispatch(receiptHandles []string) {
+	if len(receiptHandles) == 0 {
+		return
+	}
+	b.sending.Add(1)
+	go func() {
+		defer b.sending.Done()
+		b.send(receiptHandles)
+	}()
+}
+
+func (b *deleteBuffer) send(receiptHandles []string) {
+	size := b.opt.DeleteBuffer.Size
+	for start := 0; start < len(receiptHandles); start += size {
+		end := start + size
+		if end > len(receiptHandles) {
+			end = len(receiptHandles)
+		}
+		b.sendBatch(receiptHandles[start:end])
+	}
+}
+
+func (b *deleteBuffer) sendBatch(receiptHandles []string) {
+	policy := b.opt.DeleteBuffer
+	loggerInfo(b.opt.DebugMode, "Deleting processed messages size:", len(receiptHandles))
+	for attempt := 1; ; attempt++ {
+		entries := make([]DeleteMessageBatchRequestEntry, len(receiptHandles))
+		for i, receiptHandle := range receiptHandles {
+			entries[i] = DeleteMessageBatchRequestEntry{Id: strconv.Itoa(i), ReceiptHandle: receiptHandle}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		output, err := b.client.DeleteMessageBatch(ctx, DeleteMessageBatchInput{
+			QueueUrl: b.queueUrl,
+			Entries:  entries,
+		}, &b.opt.Default)
+		cancel()
+		errs := map[string]error{}
+		if err != nil && awsretry.IsErrorRetryables(awsretry.DefaultRetryables).IsErrorRetryable(err) != aws.TrueTernary {
+			// like a queue that does not exist or an access denied, the error is the same on every attempt
+			for _, receiptHandle := range receiptHandles {
+				b.reportDeleteError(receiptHandle, attempt, err)
+			}
+			return
+		} else if err != nil {
+			for _, receiptHandle := range receiptHandles {
+				errs[receiptHandle] = err
+			}
+		} else {
+			for _, failed := range output.Failed {
+				i, _ := strconv.Atoi(aws.ToString(failed.Id))
+				if i < 0 || i >= len(receiptHandles) {
+					continue
+				}
+				errEntry := errors.New(fmt.Sprint(aws.ToString(failed.Code), ": ", aws.ToString(failed.Message)))
+				if failed.SenderFault {
+					b.reportDeleteError(receiptHandles[i], attempt, errEntry)
+					continue
+				}
+				errs[receiptHandles[i]] = errEntry
+			}
+		}
+		if len(errs) == 0 {
+			return
+		}
+		receiptHandles = receiptHandles[:0:0]
+		for receiptHandle, errEntry := range errs {
+			if attempt >= policy.MaxAttempts {
+				b.reportDeleteError(receiptHandle, attempt, errEntry)
+				continue
+			}
+			receiptHandles = append(receiptHandles, receiptHandle)
+		}
+		if len(receiptHandles) == 0 {
+			return
+		}
+		delay := util.CalculateBackoff(attempt, policy.Initial, policy.Max, policy.Multiplier, policy.Jitter)
+		loggerErr(b.opt.DebugMode, "Delete messages failed size:", len(receiptHandles), "trying again in", delay.String())
+		time.Sleep(delay)
+	}
+}
+
+func (b *deleteBuffer) reportDeleteError(receiptHandle string, attempt int, err error) {
+	loggerErr(b.opt.DebugMode, "Delete message error:", err, " attempt:", attempt)
+	b.reportError(&DeleteMessageError{
+		QueueUrl:      b.queueUrl,
+		ReceiptHandle: receiptHandle,
+		Attempt:       attempt,
+		Err:           err,
+	})
+}
@@ -1,6 +1,101 @@
 package sqs
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
 
 var ErrMessageBodyEmpty = errors.New("sqs: no message body passed")
 var ErrParseBody = errors.New("sqs: message parse body failed")
+var ErrEncodeBody = errors.New("sqs: message encode body failed")
+var ErrMessageTooLarge = errors.New("sqs: message size exceeds the maximum allowed of 256 KiB")
+var ErrSendMessageBatchFailed = errors.New("sqs: send message batch failed")
+var ErrDeadLetterQueueUrlEmpty = errors.New("sqs: no dead letter queue url passed in option.Consumer")
+var ErrMessageBatchFailed = errors.New("sqs: message returned as failed by the batch handler")
+var ErrMessageAlreadySettled = errors.New("sqs: message already settled by Ack or Nack")
+var ErrMessageNotSettleable = errors.New("sqs: message context was not created by a consumer")
+var ErrReceiveMessageAttemptsExceeded = errors.New("sqs: number of failed attempts to receive messages exceeded")
+var ErrMessageAttributeRequired = errors.New("sqs: required message attribute is empty")
+var ErrMessageAttributeDataType = errors.New("sqs: message attribute data type must be Binary, Number or String")
+var ErrMaxReceiveCountExceeded = errors.New("sqs: message exceeded the max receive count")
+var ErrAsyncProducerClosed = errors.New("sqs: async producer closed")
+var ErrConsumerStopped = errors.New("sqs: consumer stopped")
+var ErrForwardAttributesDropped = errors.New("sqs: message forwarded without metadata, max attributes reached")
+var ErrMessageAttributesType = errors.New("sqs: message attributes type does not match the queue")
+
+// ReceiveMessageError is the error reported by the consumer when it fails to receive messages from the queue.
+type ReceiveMessageError struct {
+	// queue url used to receive messages
+	QueueUrl string
+	// number of consecutive failed attempts
+	Attempt int
+	// error returned from AWS SQS
+	Err error
+}
+
+func (e *ReceiveMessageError) Error() string {
+	return fmt.Sprint("sqs: receive message from ", e.QueueUrl, " failed on attempt ", e.Attempt, ": ", e.Err)
+}
+
+func (e *ReceiveMessageError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the error of a consumer handler that panicked, its message has only the value passed to panic, the
+// stack trace is kept in Stack.
+type PanicError struct {
+	// value passed to panic
+	Value any
+	// stack trace of the goroutine where the panic occurred
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprint("sqs: handler panic: ", e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// DecodeMessageError is the error of a received message which could not be converted to the Body or to the
+// MessageAttributes, the message is not settled and appears again in the queue after the visibility timeout.
+type DecodeMessageError struct {
+	// queue url of the message
+	QueueUrl string
+	// message received from AWS SQS
+	Message types.Message
+	// error of the conversion, like ErrParseBody or ErrMessageAttributeRequired
+	Err error
+}
+
+func (e *DecodeMessageError) Error() string {
+	return fmt.Sprint("sqs: decode message ", aws.ToString(e.Message.MessageId), " from ", e.QueueUrl, " failed: ", e.Err)
+}
+
+func (e *DecodeMessageError) Unwrap() error {
+	return e.Err
+}
+
+// DeleteMessageError is the error reported by the consumer when it fails to delete a processed message from the queue.
+type DeleteMessageError struct {
+	// queue url of the message
+	QueueUrl string
+	// receipt handle of the message
+	ReceiptHandle string
+	// number of attempts to delete the message
+	Attempt int
+	// error returned from AWS SQS
+	Err error
+}
+
+func (e *DeleteMessageError) Error() string {
+	return fmt.Sprint("sqs: delete message from ", e.QueueUrl, " failed on attempt ", e.Attempt, ": ", e.Err)
+}
+
+func (e *DeleteMessageError) Unwrap() error {
+	return e.Err
+}
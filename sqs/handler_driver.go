@@ -0,0 +1,333 @@
+package sqs
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/util"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HandlerOutcome is what the consumer does with the message after running the handler, reported by HandlerDriver.Run.
+type HandlerOutcome int
+
+const (
+	// HandlerOutcomeAck the handler returned nil with option.Consumer.DeleteMessageProcessedSuccess set, or called
+	// Context.Ack
+	HandlerOutcomeAck HandlerOutcome = iota + 1
+	// HandlerOutcomeRetry the handler returned RetryAfter or called Context.Nack, the message appears again in the
+	// queue after HandlerRunResult.Delay
+	HandlerOutcomeRetry
+	// HandlerOutcomeDrop the handler returned Drop
+	HandlerOutcomeDrop
+	// HandlerOutcomeDeadLetter the handler returned DeadLetter, the message is forwarded to
+	// option.Consumer.DeadLetterQueueUrl
+	HandlerOutcomeDeadLetter
+	// HandlerOutcomeError the handler returned an error, the message appears again in the queue after the visibility
+	// timeout, or after HandlerRunResult.Delay if option.Consumer.RedeliveryBackoff is set
+	HandlerOutcomeError
+	// HandlerOutcomePanic the handler or a middleware panicked, HandlerRunResult.Err is a *PanicError and the message
+	// fails like in HandlerOutcomeError
+	HandlerOutcomePanic
+	// HandlerOutcomeTimeout the option.Consumer.ConsumerMessageTimeout was reached before the handler returned, the
+	// message is not settled
+	HandlerOutcomeTimeout
+	// HandlerOutcomeKeep the handler returned nil without option.Consumer.DeleteMessageProcessedSuccess set, the message
+	// is left in the queue and appears again after the visibility timeout
+	HandlerOutcomeKeep
+	// HandlerOutcomeQuarantine the message exceeded option.Consumer.MaxReceiveCount, it's sent to
+	// option.Consumer.QuarantineQueueUrl without running the handler, HandlerRunResult.Err is
+	// ErrMaxReceiveCountExceeded
+	HandlerOutcomeQuarantine
+)
+
+// HandlerDriver runs a HandlerConsumerFunc without a queue, to test it. The message is built from a Go value with the
+// same encoding used by the producer (see SendMessage), and converted to the Context with the same decoding used by
+// the consumer (see ReceiveMessage), then the handler is run with the middlewares and the timeout of the
+// option.Consumer, reporting what the consumer would do with the message.
+//
+// Example usage:
+//
+//	opt := option.NewConsumer().SetDeleteMessageProcessedSuccess(true).SetMiddlewares(m)
+//	driver := sqs.NewHandlerDriver[test, testAttributes](handler, opt)
+//	result, err := driver.Run(ctx, queueUrl, test{Name: "Test"}, option.NewProducer().SetMessageAttributes(att))
+//	if err == nil && result.Outcome != sqs.HandlerOutcomeAck {
+//		t.Errorf("Run() outcome = %s, err = %v", result.Outcome, result.Err)
+//	}
+type HandlerDriver[Body, MessageAttributes any] struct {
+	handler      HandlerConsumerFunc[Body, MessageAttributes]
+	opt          *option.Consumer
+	receiveCount int
+}
+
+// HandlerRunResult is the result of HandlerDriver.Run.
+type HandlerRunResult[Body, MessageAttributes any] struct {
+	// what the consumer does with the message
+	Outcome HandlerOutcome
+	// error returned by the handler, the *PanicError if it panicked or the error of the context on timeout, when the
+	// message was settled by Context.Ack or Context.Nack it is ignored by the consumer
+	Err error
+	// delay until the message appears again in the queue, filled on HandlerOutcomeRetry, and on HandlerOutcomeError
+	// and HandlerOutcomePanic if option.Consumer.RedeliveryBackoff is set
+	Delay time.Duration
+	// reason passed to DeadLetter
+	Reason string
+	// visibility timeouts passed to Context.ExtendVisibility, in the order of the calls
+	VisibilityExtensions []time.Duration
+	// message as it would be received from the queue
+	Message types.Message
+	// context passed to the handler, on HandlerOutcomeTimeout the handler may still be running, nil on
+	// HandlerOutcomeQuarantine
+	Context *Context[Body, MessageAttributes]
+}
+
+type handlerRecorder struct {
+	mutex      sync.Mutex
+	outcome    HandlerOutcome
+	delay      time.Duration
+	extensions []time.Duration
+}
+
+// NewHandlerDriver creates a HandlerDriver for the handler, with the options used by the consumer to convert the
+// message and run the handler, like option.Consumer.Codec, option.Consumer.Middlewares and
+// option.Consumer.ConsumerMessageTimeout.
+func NewHandlerDriver[Body, MessageAttributes any](
+	handler HandlerConsumerFunc[Body, MessageAttributes],
+	opts ...*option.Consumer,
+) *HandlerDriver[Body, MessageAttributes] {
+	return &HandlerDriver[Body, MessageAttributes]{
+		handler:      handler,
+		opt:          option.GetConsumerByParams(opts),
+		receiveCount: 1,
+	}
+}
+
+// NewSimpleHandlerDriver creates a HandlerDriver for the simple handler, same as NewHandlerDriver.
+func NewSimpleHandlerDriver[Body any](
+	handler HandlerSimpleConsumerFunc[Body],
+	opts ...*option.Consumer,
+) *HandlerDriver[Body, map[string]types.MessageAttributeValue] {
+	return NewHandlerDriver(initHandleConsumerFunc[Body, map[string]types.MessageAttributeValue](handler), opts...)
+}
+
+// SetReceiveCount sets the ApproximateReceiveCount attribute of the messages, used to test redeliveries.
+// default: 1
+func (d *HandlerDriver[Body, MessageAttributes]) SetReceiveCount(i int) *HandlerDriver[Body, MessageAttributes] {
+	d.receiveCount = i
+	return d
+}
+
+// Run builds a message from the body and the options informed, like SendMessage, converts it to the Context, like
+// ReceiveMessage, and runs the handler once. Like the consumer, if the receive count (see SetReceiveCount) exceeds
+// option.Consumer.MaxReceiveCount with option.Consumer.QuarantineQueueUrl filled, the handler is not run and the
+// outcome is HandlerOutcomeQuarantine.
+//
+// # Parameters
+//
+// - ctx: context of the handler, the option.Consumer.ConsumerMessageTimeout is applied on it
+// - queueUrl: url of the queue filled in the Context
+// - body: body of the message, converted like in SendMessage
+// - opts: options of the producer used to build the message, like the message attributes
+//
+// # Returns
+//
+// - result: what the consumer does with the message and the error of the handler
+// - error: error of the conversion of the message, the same errors which make the consumer treat the message as
+// poison (see option.Consumer.OnPoisonMessage), in this case the handler is not run
+func (d *HandlerDriver[Body, MessageAttributes]) Run(ctx context.Context, queueUrl string, body any,
+	opts ...*option.Producer) (*HandlerRunResult[Body, MessageAttributes], error) {
+	input, err := prepareMessageInput(queueUrl, body, option.GetProducerByParams(opts))
+	if err != nil {
+		return nil, err
+	}
+	message := d.prepareMessage(input)
+	job := &consumerJob{opt: d.opt}
+	if err = job.checkMaxReceiveCount(message); err != nil {
+		if d.opt.OnPoisonMessage != nil {
+			d.opt.OnPoisonMessage(queueUrl, message, err)
+		}
+		loggerInfo(d.opt.DebugMode, "Message", *message.MessageId, "exceeded the max receive count, handler not run")
+		return &HandlerRunResult[Body, MessageAttributes]{
+			Outcome: HandlerOutcomeQuarantine,
+			Err:     err,
+			Message: message,
+		}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.opt.ConsumerMessageTimeout)
+	defer cancel()
+	ctxConsumer, err := prepareContextConsumer[Body, MessageAttributes](ctx, queueUrl, message, d.opt)
+	if err != nil {
+		return nil, err
+	}
+	recorder := &handlerRecorder{}
+	ctxConsumer.settlement = recorder.newSettlement()
+	middlewares := append([]option.Middleware{job.recoverPanic}, getMiddlewares(d.opt)...)
+	messageHandler := chainMiddlewares(middlewares, func(ctxHandler context.Context, _ string, _ types.Message) error {
+		ctxConsumer.Context = ctxHandler
+		return d.handler(ctxConsumer)
+	})
+	signal := make(chan struct{}, 1)
+	channel := channelMessageProcessed{
+		Signal: &signal,
+	}
+	go processHandler(ctx, func() error {
+		return messageHandler(ctx, queueUrl, message)
+	}, &channel)
+	result := &HandlerRunResult[Body, MessageAttributes]{
+		Message: message,
+		Context: ctxConsumer,
+	}
+	select {
+	case <-ctx.Done():
+		result.Outcome = HandlerOutcomeTimeout
+		result.Err = ctx.Err()
+		break
+	case <-*channel.Signal:
+		result.Err = channel.Err
+		if ctxConsumer.settlement.markSettled() {
+			d.settleMessage(job, message, result)
+		}
+		break
+	}
+	outcome, delay, extensions := recorder.snapshot()
+	if outcome != 0 {
+		result.Outcome = outcome
+		result.Delay = delay
+	}
+	result.VisibilityExtensions = extensions
+	loggerInfo(d.opt.DebugMode, "Handler of message", *message.MessageId, "finished with outcome:", result.Outcome)
+	return result, nil
+}
+
+func (d *HandlerDriver[Body, MessageAttributes]) prepareMessage(input *sqs.SendMessageInput) types.Message {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	md5OfBody := md5.Sum([]byte(aws.ToString(input.MessageBody)))
+	attributes := map[string]string{
+		string(types.MessageSystemAttributeNameApproximateReceiveCount):          strconv.Itoa(d.receiveCount),
+		string(types.MessageSystemAttributeNameApproximateFirstReceiveTimestamp): now,
+		string(types.MessageSystemAttributeNameSentTimestamp):                    now,
+	}
+	if input.MessageGroupId != nil {
+		attributes[string(types.MessageSystemAttributeNameMessageGroupId)] = *input.MessageGroupId
+	}
+	if input.MessageDeduplicationId != nil {
+		attributes[string(types.MessageSystemAttributeNameMessageDeduplicationId)] = *input.MessageDeduplicationId
+	}
+	return types.Message{
+		MessageId:         aws.String(util.NewUUID()),
+		ReceiptHandle:     aws.String(util.NewUUID()),
+		Body:              input.MessageBody,
+		MD5OfBody:         aws.String(hex.EncodeToString(md5OfBody[:])),
+		Attributes:        attributes,
+		MessageAttributes: input.MessageAttributes,
+	}
+}
+
+// settleMessage fills the outcome of the error returned by the handler, from the decision of
+// consumerJob.handlerResult used by the consumer.
+func (d *HandlerDriver[Body, MessageAttributes]) settleMessage(
+	job *consumerJob,
+	message types.Message,
+	result *HandlerRunResult[Body, MessageAttributes],
+) {
+	handlerResult, err := job.handlerResult(message, result.Err)
+	var returnedResult *HandlerResult
+	var panicErr *PanicError
+	if err != nil {
+		result.Outcome = HandlerOutcomeError
+		result.Err = err
+		job.reportError(err)
+		return
+	} else if result.Err == nil {
+		result.Outcome = HandlerOutcomeKeep
+		if handlerResult != nil {
+			result.Outcome = HandlerOutcomeAck
+		}
+		return
+	} else if errors.As(result.Err, &returnedResult) {
+		switch handlerResult.action {
+		case handlerActionRetryAfter:
+			result.Outcome = HandlerOutcomeRetry
+			result.Delay = handlerResult.delay
+		case handlerActionDrop:
+			result.Outcome = HandlerOutcomeDrop
+		case handlerActionDeadLetter:
+			result.Outcome = HandlerOutcomeDeadLetter
+			result.Reason = handlerResult.reason
+		}
+		return
+	} else if errors.As(result.Err, &panicErr) {
+		result.Outcome = HandlerOutcomePanic
+	} else {
+		result.Outcome = HandlerOutcomeError
+	}
+	if handlerResult != nil {
+		result.Delay = handlerResult.delay
+	}
+}
+
+func (r *handlerRecorder) newSettlement() *messageSettlement {
+	return &messageSettlement{
+		ack: func() error {
+			r.record(HandlerOutcomeAck, 0)
+			return nil
+		},
+		nack: func(delay time.Duration) error {
+			r.record(HandlerOutcomeRetry, delay)
+			return nil
+		},
+		extend: func(d time.Duration) error {
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
+			r.extensions = append(r.extensions, d)
+			return nil
+		},
+	}
+}
+
+func (r *handlerRecorder) record(outcome HandlerOutcome, delay time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.outcome = outcome
+	r.delay = delay
+}
+
+// snapshot returns the settlement made by Context.Ack or Context.Nack, with zero outcome if none was made, and the
+// calls of Context.ExtendVisibility.
+func (r *handlerRecorder) snapshot() (HandlerOutcome, time.Duration, []time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.outcome, r.delay, append([]time.Duration(nil), r.extensions...)
+}
+
+func (o HandlerOutcome) String() string {
+	switch o {
+	case HandlerOutcomeAck:
+		return "ack"
+	case HandlerOutcomeRetry:
+		return "retry"
+	case HandlerOutcomeDrop:
+		return "drop"
+	case HandlerOutcomeDeadLetter:
+		return "dead letter"
+	case HandlerOutcomeError:
+		return "error"
+	case HandlerOutcomePanic:
+		return "panic"
+	case HandlerOutcomeTimeout:
+		return "timeout"
+	case HandlerOutcomeKeep:
+		return "keep"
+	case HandlerOutcomeQuarantine:
+		return "quarantine"
+	default:
+		return "unknown"
+	}
+}
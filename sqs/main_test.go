@@ -5,8 +5,15 @@ import (
 	"errors"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
 	"github.com/GabrielHCataldo/go-logger/logger"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -51,6 +58,106 @@ type testSimpleConsumer[Body any] struct {
 	async    bool
 }
 
+type testBatchConsumer[Body, MessageAttributes any] struct {
+	name     string
+	queueUrl string
+	handler  HandlerBatchConsumerFunc[Body, MessageAttributes]
+	opts     []*option.Consumer
+	wantErr  bool
+	async    bool
+}
+
+type testProducerBatch struct {
+	name     string
+	queueUrl string
+	entries  []SendMessageBatchEntry
+	opts     []*option.Producer
+	wantErr  bool
+}
+
+type testSendMessageBatchErr struct {
+	name      string
+	err       error
+	wantCalls int
+}
+
+type testDecodeBody struct {
+	name    string
+	body    string
+	opt     *option.Consumer
+	wantErr string
+}
+
+type testQuarantineMessage struct {
+	name              string
+	body              string
+	receiveCount      string
+	messageAttributes int
+	wantErr           error
+	wantDropped       bool
+}
+
+type testHandlerResult struct {
+	name              string
+	handler           HandlerConsumerFunc[test, messageAttTest]
+	opt               *option.Consumer
+	receiveCount      string
+	messageAttributes int
+	wantDeleted       bool
+	wantVisibilities  []int32
+	wantForwarded     bool
+	wantMetadata      []string
+	wantErr           error
+}
+
+type testDeleteBuffer struct {
+	name           string
+	failures       int
+	senderFault    bool
+	err            error
+	maxAttempts    int
+	wantDeleted    bool
+	wantErrAttempt int
+}
+
+type testVisibilityHeartbeatSettle struct {
+	name           string
+	messages       int
+	receive        func(ctx context.Context, c *Client, opt *option.Consumer) error
+	wantVisibility int32
+}
+
+type testMiddleware struct {
+	name    string
+	handler HandlerConsumerFunc[test, messageAttTest]
+	wantErr bool
+}
+
+type testMiddlewareKey struct{}
+
+type testHandlerPanic struct {
+	name    string
+	receive func(ctx context.Context, c *Client, opt *option.Consumer) error
+}
+
+type testHandlerDriver struct {
+	name         string
+	handler      HandlerConsumerFunc[test, messageAttTest]
+	opt          *option.Consumer
+	receiveCount int
+	wantOutcome  HandlerOutcome
+	wantDelay    time.Duration
+	wantErr      bool
+}
+
+type testReceive struct {
+	name        string
+	bodies      []any
+	opt         *option.Consumer
+	wantSuccess int
+	wantFailed  int
+}
+
 type testCreateQueue struct {
 	name      string
 	queueName string
@@ -200,6 +307,17 @@ type messageAttTest struct {
 	Any         any
 	EmptyString string `json:"emptyString,omitempty"`
 	HideString  string `json:"-"`
+	Bytes       []byte `json:"bytes,omitempty"`
+}
+
+type messageAttTagTest struct {
+	Name    string  `json:"name" sqs:"userName,required"`
+	Balance float64 `sqs:"balance,type=Number.float"`
+	Count   int     `sqs:"count"`
+	Id      string  `sqs:"id,type=String.uuid,omitempty"`
+	Payload []byte  `sqs:"payload,omitempty"`
+	Code    string  `sqs:"code,type=Binary"`
+	Hidden  string  `sqs:"-"`
 }
 
 type bank struct {
@@ -208,6 +326,132 @@ type bank struct {
 	Balance float64 `json:"balance,omitempty"`
 }
 
+type mockApi struct {
+	API
+	mutex          sync.Mutex
+	messages       []types.Message
+	sendInputs     []*sqs.SendMessageInput
+	batchSizes     []int
+	retried        map[string]bool
+	receiptHandles []string
+	visibilities   []*sqs.ChangeMessageVisibilityInput
+	deleteFailures map[string]int
+	senderFault    bool
+	// error returned by each SendMessageBatch call
+	sendBatchErr error
+	// error returned by each DeleteMessageBatch call
+	deleteBatchErr error
+	// duration of each ChangeMessageVisibility call
+	visibilityDelay time.Duration
+}
+
+func (m *mockApi) SendMessageBatch(_ context.Context, params *sqs.SendMessageBatchInput, _ ...func(*sqs.Options)) (
+	*sqs.SendMessageBatchOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.batchSizes = append(m.batchSizes, len(params.Entries))
+	if m.sendBatchErr != nil {
+		return nil, m.sendBatchErr
+	}
+	output := &sqs.SendMessageBatchOutput{}
+	for _, entry := range params.Entries {
+		if strings.Contains(*entry.MessageBody, "retry") && !m.retried[*entry.Id] {
+			m.retried[*entry.Id] = true
+			output.Failed = append(output.Failed, types.BatchResultErrorEntry{
+				Code:        aws.String("InternalError"),
+				Id:          entry.Id,
+				SenderFault: false,
+			})
+			continue
+		}
+		output.Successful = append(output.Successful, types.SendMessageBatchResultEntry{
+			Id:        entry.Id,
+			MessageId: aws.String("mock-" + *entry.Id),
+		})
+	}
+	return output, nil
+}
+
+func (m *mockApi) SendMessage(_ context.Context, params *sqs.SendMessageInput, _ ...func(*sqs.Options)) (
+	*sqs.SendMessageOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sendInputs = append(m.sendInputs, params)
+	return &sqs.SendMessageOutput{MessageId: aws.String(strconv.Itoa(len(m.sendInputs)))}, nil
+}
+
+func (m *mockApi) ReceiveMessage(_ context.Context, _ *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (
+	*sqs.ReceiveMessageOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	output := &sqs.ReceiveMessageOutput{Messages: m.messages}
+	m.messages = nil
+	return output, nil
+}
+
+func (m *mockApi) DeleteMessageBatch(_ context.Context, params *sqs.DeleteMessageBatchInput,
+	_ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.deleteBatchErr != nil {
+		return nil, m.deleteBatchErr
+	}
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range params.Entries {
+		if m.deleteFailures[*entry.ReceiptHandle] > 0 {
+			m.deleteFailures[*entry.ReceiptHandle]--
+			output.Failed = append(output.Failed, types.BatchResultErrorEntry{
+				Code:        aws.String("InternalError"),
+				Id:          entry.Id,
+				SenderFault: m.senderFault,
+			})
+			continue
+		}
+		m.receiptHandles = append(m.receiptHandles, *entry.ReceiptHandle)
+		output.Successful = append(output.Successful, types.DeleteMessageBatchResultEntry{Id: entry.Id})
+	}
+	return output, nil
+}
+
+type receiveRecorderApi struct {
+	API
+	mutex               sync.Mutex
+	maxNumberOfMessages []int32
+}
+
+func (r *receiveRecorderApi) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput,
+	optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	r.mutex.Lock()
+	r.maxNumberOfMessages = append(r.maxNumberOfMessages, params.MaxNumberOfMessages)
+	r.mutex.Unlock()
+	return r.API.ReceiveMessage(ctx, params, optFns...)
+}
+
+func (m *mockApi) ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput,
+	_ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	select {
+	case <-time.After(m.visibilityDelay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.visibilities = append(m.visibilities, params)
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func (m *mockApi) changedVisibilities() []*sqs.ChangeMessageVisibilityInput {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.visibilities
+}
+
+func (m *mockApi) deletedReceiptHandles() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.receiptHandles
+}
+
 func TestMain(t *testing.M) {
 	t.Run()
 	deleteQueueCreateTest()
@@ -215,6 +459,100 @@ func TestMain(t *testing.M) {
 	cancelMessageMoveTaskTest()
 }
 
+func initMockApi() *mockApi {
+	return &mockApi{
+		retried: map[string]bool{},
+		messages: []types.Message{
+			{
+				MessageId:     aws.String("mock-message-id"),
+				ReceiptHandle: aws.String("mock-receipt-handle"),
+				MD5OfBody:     aws.String(""),
+				Body:          aws.String(`{"name":"Test Name"}`),
+			},
+		},
+	}
+}
+
+func initAwsConfigTest() aws.Config {
+	return aws.Config{
+		Region: "us-east-1",
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"}, nil
+		}),
+	}
+}
+
+func initApiOptions() []func(*middleware.Stack) error {
+	return []func(*middleware.Stack) error{
+		func(stack *middleware.Stack) error {
+			return nil
+		},
+	}
+}
+
+func initListTestProducerBatch() []testProducerBatch {
+	return []testProducerBatch{
+		{
+			name:     "valid request",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			entries: []SendMessageBatchEntry{
+				{Body: initTestStruct()},
+				{Body: "body test", Opts: []*option.Producer{option.NewProducer().SetDelaySeconds(time.Second)}},
+				{Body: initTestStruct(), Opts: []*option.Producer{option.NewProducer().SetMessageAttributes(initTestMap())}},
+			},
+			opts: []*option.Producer{
+				nil,
+				option.NewProducer().SetDebugMode(true),
+				option.NewProducer().SetBatchMaxAttempts(2),
+			},
+			wantErr: false,
+		},
+		{
+			name:     "valid request fifo",
+			queueUrl: os.Getenv(sqsQueueTestFifoUrl),
+			entries: []SendMessageBatchEntry{
+				{Body: initTestStruct(), Opts: []*option.Producer{option.NewProducer().SetMessageDeduplicationId("1")}},
+				{Body: initTestStruct(), Opts: []*option.Producer{option.NewProducer().SetMessageDeduplicationId("2")}},
+			},
+			opts: []*option.Producer{
+				option.NewProducer().SetMessageGroupId("group"),
+			},
+			wantErr: false,
+		},
+		{
+			name:     "invalid entry",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			entries: []SendMessageBatchEntry{
+				{Body: initTestStruct()},
+				{Body: ""},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "invalid queue URL",
+			queueUrl: "https://google.com",
+			entries:  []SendMessageBatchEntry{{Body: initTestStruct()}},
+			opts:     []*option.Producer{option.NewProducer().SetBatchMaxAttempts(1)},
+			wantErr:  true,
+		},
+	}
+}
+
+func initListTestSendMessageBatchErr() []testSendMessageBatchErr {
+	return []testSendMessageBatchErr{
+		{
+			name:      "retryable",
+			err:       &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"},
+			wantCalls: 3,
+		},
+		{
+			name:      "not retryable",
+			err:       &types.QueueDoesNotExist{Message: aws.String("The specified queue does not exist.")},
+			wantCalls: 1,
+		},
+	}
+}
+
 func initListTestProducer() []testProducer {
 	msgAttTest := initMessageAttTest()
 	return []testProducer{
@@ -336,6 +674,41 @@ func initListTestConsumer[Body, MessageAttributes any]() []testConsumer[Body, Me
 			opts:     initOptionsConsumerDefault(),
 			wantErr:  false,
 		},
+		{
+			name:     "success retry after",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			handler:  initHandleConsumerRetryAfter[Body, MessageAttributes],
+			opts:     initOptionsConsumerDefault(),
+			wantErr:  false,
+		},
+		{
+			name:     "success drop",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			handler:  initHandleConsumerDrop[Body, MessageAttributes],
+			opts:     initOptionsConsumerDefault(),
+			wantErr:  false,
+		},
+		{
+			name:     "success dead letter",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			handler:  initHandleConsumerDeadLetter[Body, MessageAttributes],
+			opts:     initOptionsConsumerDefault(),
+			wantErr:  false,
+		},
+		{
+			name:     "success manual ack",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			handler:  initHandleConsumerAck[Body, MessageAttributes],
+			opts:     initOptionsConsumerDefault(),
+			wantErr:  false,
+		},
+		{
+			name:     "success manual nack",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			handler:  initHandleConsumerNack[Body, MessageAttributes],
+			opts:     initOptionsConsumerDefault(),
+			wantErr:  false,
+		},
 		{
 			name:     "success async",
 			queueUrl: os.Getenv(sqsQueueTestUrl),
@@ -370,6 +743,54 @@ func initListTestConsumer[Body, MessageAttributes any]() []testConsumer[Body, Me
 	}
 }
 
+func initListTestBatchConsumer[Body, MessageAttributes any]() []testBatchConsumer[Body, MessageAttributes] {
+	return []testBatchConsumer[Body, MessageAttributes]{
+		{
+			name:     "success",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			handler:  initHandleBatchConsumer[Body, MessageAttributes],
+			opts:     initOptionsConsumerDefault(),
+			wantErr:  false,
+		},
+		{
+			name:     "success partial failure",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			handler:  initHandleBatchConsumerPartialFailure[Body, MessageAttributes],
+			opts:     initOptionsConsumerDefault(),
+			wantErr:  false,
+		},
+		{
+			name:     "success error consumer",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			handler:  initHandleBatchConsumerWithErr[Body, MessageAttributes],
+			opts:     initOptionsConsumerDefault(),
+			wantErr:  false,
+		},
+		{
+			name:     "success async",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			handler:  initHandleBatchConsumer[Body, MessageAttributes],
+			opts:     initOptionsConsumerDefault(),
+			async:    true,
+			wantErr:  false,
+		},
+		{
+			name:     "failed parse body",
+			queueUrl: os.Getenv(sqsQueueTestStringUrl),
+			handler:  initHandleBatchConsumer[Body, MessageAttributes],
+			opts:     initOptionsConsumerDefault(),
+			wantErr:  false,
+		},
+		{
+			name:     "failed",
+			queueUrl: "https://google.com/",
+			handler:  initHandleBatchConsumer[Body, MessageAttributes],
+			opts:     initOptionsConsumerWithErr(),
+			wantErr:  true,
+		},
+	}
+}
+
 func initListTestSimpleConsumer[Body any]() []testSimpleConsumer[Body] {
 	return []testSimpleConsumer[Body]{
 		{
@@ -386,6 +807,13 @@ func initListTestSimpleConsumer[Body any]() []testSimpleConsumer[Body] {
 			opts:     initOptionsConsumerDefault(),
 			wantErr:  false,
 		},
+		{
+			name:     "success manual ack",
+			queueUrl: os.Getenv(sqsQueueTestUrl),
+			handler:  initSimpleHandleConsumerAck[Body],
+			opts:     initOptionsConsumerDefault(),
+			wantErr:  false,
+		},
 		{
 			name:     "success async",
 			queueUrl: os.Getenv(sqsQueueTestUrl),
@@ -420,6 +848,391 @@ func initListTestSimpleConsumer[Body any]() []testSimpleConsumer[Body] {
 	}
 }
 
+func initListTestDecodeBody() []testDecodeBody {
+	strict := option.NewConsumer().SetStrictBodyDecoding(true)
+	return []testDecodeBody{
+		{
+			name: "empty struct",
+			body: `{}`,
+			opt:  strict,
+		},
+		{
+			name: "unknown field allowed",
+			body: `{"name":"Test Name","unknown":1}`,
+			opt:  strict,
+		},
+		{
+			name:    "unknown field disallowed",
+			body:    `{"name":"Test Name","unknown":1}`,
+			opt:     option.NewConsumer().SetStrictBodyDecoding(true).SetDisallowUnknownFields(true),
+			wantErr: `unknown field "unknown"`,
+		},
+		{
+			name:    "type mismatch",
+			body:    `{"bank":{"balance":"invalid"}}`,
+			opt:     strict,
+			wantErr: "test.bank.balance",
+		},
+		{
+			name:    "invalid data after value",
+			body:    `{} {}`,
+			opt:     strict,
+			wantErr: "invalid data after top-level value",
+		},
+		{
+			name:    "not strict empty struct",
+			body:    `{}`,
+			opt:     option.NewConsumer(),
+			wantErr: ErrParseBody.Error(),
+		},
+	}
+}
+
+func initListTestQuarantineMessage() []testQuarantineMessage {
+	return []testQuarantineMessage{
+		{
+			name:         "undecodable body",
+			body:         `{"name":1}`,
+			receiveCount: "1",
+			wantErr:      ErrParseBody,
+		},
+		{
+			name:         "max receive count exceeded",
+			body:         `{"name":"Test Name"}`,
+			receiveCount: "4",
+			wantErr:      ErrMaxReceiveCountExceeded,
+		},
+		{
+			name:              "undecodable body with 10 message attributes",
+			body:              `{"name":1}`,
+			receiveCount:      "1",
+			messageAttributes: 10,
+			wantErr:           ErrParseBody,
+			wantDropped:       true,
+		},
+	}
+}
+
+func initListTestHandlerResult() []testHandlerResult {
+	return []testHandlerResult{
+		{
+			name:        "drop",
+			handler:     initHandleConsumerDrop[test, messageAttTest],
+			wantDeleted: true,
+		},
+		{
+			name:             "retry after",
+			handler:          initHandleConsumerRetryAfter[test, messageAttTest],
+			wantVisibilities: []int32{2},
+		},
+		{
+			name:          "dead letter",
+			handler:       initHandleConsumerDeadLetter[test, messageAttTest],
+			opt:           option.NewConsumer().SetDeadLetterQueueUrl("https://sqs.mock/dlq"),
+			wantDeleted:   true,
+			wantForwarded: true,
+			wantMetadata:  []string{FailureReasonAttributeName, SourceQueueUrlAttributeName},
+		},
+		{
+			name:              "dead letter with 9 message attributes",
+			handler:           initHandleConsumerDeadLetter[test, messageAttTest],
+			opt:               option.NewConsumer().SetDeadLetterQueueUrl("https://sqs.mock/dlq"),
+			messageAttributes: 9,
+			wantDeleted:       true,
+			wantForwarded:     true,
+			wantMetadata:      []string{FailureReasonAttributeName},
+			wantErr:           ErrForwardAttributesDropped,
+		},
+		{
+			name:              "dead letter with 10 message attributes",
+			handler:           initHandleConsumerDeadLetter[test, messageAttTest],
+			opt:               option.NewConsumer().SetDeadLetterQueueUrl("https://sqs.mock/dlq"),
+			messageAttributes: 10,
+			wantDeleted:       true,
+			wantForwarded:     true,
+			wantErr:           ErrForwardAttributesDropped,
+		},
+		{
+			name:    "dead letter without queue url",
+			handler: initHandleConsumerDeadLetter[test, messageAttTest],
+			wantErr: ErrDeadLetterQueueUrlEmpty,
+		},
+		{
+			name:    "error",
+			handler: initHandleConsumerWithErr[test, messageAttTest],
+		},
+		{
+			name:    "error with redelivery backoff",
+			handler: initHandleConsumerWithErr[test, messageAttTest],
+			opt: option.NewConsumer().SetRedeliveryBackoff(option.Backoff{
+				Initial: 2 * time.Second,
+				Jitter:  -1,
+			}),
+			receiveCount:     "3",
+			wantVisibilities: []int32{8},
+		},
+		{
+			name:    "error with redelivery backoff under 1 second",
+			handler: initHandleConsumerWithErr[test, messageAttTest],
+			opt: option.NewConsumer().SetRedeliveryBackoff(option.Backoff{
+				Initial: 300 * time.Millisecond,
+				Jitter:  -1,
+			}),
+			receiveCount:     "1",
+			wantVisibilities: []int32{1},
+		},
+	}
+}
+
+func initListTestDeleteBuffer() []testDeleteBuffer {
+	return []testDeleteBuffer{
+		{
+			name:        "deleted",
+			maxAttempts: 3,
+			wantDeleted: true,
+		},
+		{
+			name:        "deleted after retry",
+			failures:    2,
+			maxAttempts: 3,
+			wantDeleted: true,
+		},
+		{
+			name:           "attempts exceeded",
+			failures:       3,
+			maxAttempts:    2,
+			wantErrAttempt: 2,
+		},
+		{
+			name:           "sender fault",
+			failures:       1,
+			senderFault:    true,
+			maxAttempts:    3,
+			wantErrAttempt: 1,
+		},
+		{
+			name:           "retryable request error",
+			err:            &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"},
+			maxAttempts:    3,
+			wantErrAttempt: 3,
+		},
+		{
+			name:           "request error not retryable",
+			err:            &types.QueueDoesNotExist{Message: aws.String("The specified queue does not exist.")},
+			maxAttempts:    3,
+			wantErrAttempt: 1,
+		},
+	}
+}
+
+func initListTestVisibilityHeartbeatSettle() []testVisibilityHeartbeatSettle {
+	return []testVisibilityHeartbeatSettle{
+		{
+			name:     "handler",
+			messages: 1,
+			receive: func(ctx context.Context, c *Client, opt *option.Consumer) error {
+				return ReceiveMessageWithClient(ctx, c, "https://sqs.mock/queue",
+					func(ctx *Context[test, messageAttTest]) error {
+						time.Sleep(1100 * time.Millisecond)
+						return RetryAfter(5 * time.Second)
+					}, opt)
+			},
+			wantVisibility: 5,
+		},
+		{
+			name:     "batch handler",
+			messages: 4,
+			receive: func(ctx context.Context, c *Client, opt *option.Consumer) error {
+				return ReceiveMessageBatchWithClient(ctx, c, "https://sqs.mock/queue",
+					func(ctx *BatchContext[test, messageAttTest]) ([]string, error) {
+						time.Sleep(1100 * time.Millisecond)
+						var failedIds []string
+						for _, message := range ctx.Messages {
+							failedIds = append(failedIds, message.Id)
+						}
+						return failedIds, nil
+					}, opt, option.NewConsumer().SetRedeliveryBackoff(option.Backoff{Initial: 5 * time.Second, Jitter: -1}))
+			},
+			wantVisibility: 5,
+		},
+	}
+}
+
+func initListTestMiddleware() []testMiddleware {
+	return []testMiddleware{
+		{
+			name:    "context value",
+			handler: initHandleConsumerMiddlewareValue[test, messageAttTest],
+			wantErr: false,
+		},
+		{
+			name:    "recovery",
+			handler: initHandleConsumerPanic[test, messageAttTest],
+			wantErr: true,
+		},
+	}
+}
+
+func initListTestHandlerPanic() []testHandlerPanic {
+	return []testHandlerPanic{
+		{
+			name: "handler",
+			receive: func(ctx context.Context, c *Client, opt *option.Consumer) error {
+				return ReceiveMessageWithClient(ctx, c, "https://sqs.mock/queue",
+					initHandleConsumerPanic[test, messageAttTest], opt)
+			},
+		},
+		{
+			name: "batch handler",
+			receive: func(ctx context.Context, c *Client, opt *option.Consumer) error {
+				return ReceiveMessageBatchWithClient(ctx, c, "https://sqs.mock/queue",
+					initHandleBatchConsumerPanic[test, messageAttTest], opt)
+			},
+		},
+	}
+}
+
+func initListTestHandlerDriver() []testHandlerDriver {
+	return []testHandlerDriver{
+		{
+			name:        "ack",
+			handler:     initHandleConsumer[test, messageAttTest],
+			opt:         option.NewConsumer().SetDeleteMessageProcessedSuccess(true),
+			wantOutcome: HandlerOutcomeAck,
+		},
+		{
+			name:        "keep",
+			handler:     initHandleConsumer[test, messageAttTest],
+			wantOutcome: HandlerOutcomeKeep,
+		},
+		{
+			name:        "error",
+			handler:     initHandleConsumerWithErr[test, messageAttTest],
+			wantOutcome: HandlerOutcomeError,
+			wantErr:     true,
+		},
+		{
+			name:    "error with redelivery backoff",
+			handler: initHandleConsumerWithErr[test, messageAttTest],
+			opt: option.NewConsumer().SetRedeliveryBackoff(option.Backoff{
+				Initial: time.Second,
+				Max:     10 * time.Second,
+				Jitter:  -1,
+			}),
+			receiveCount: 3,
+			wantOutcome:  HandlerOutcomeError,
+			wantDelay:    4 * time.Second,
+			wantErr:      true,
+		},
+		{
+			name:        "retry after",
+			handler:     initHandleConsumerRetryAfter[test, messageAttTest],
+			wantOutcome: HandlerOutcomeRetry,
+			wantDelay:   2 * time.Second,
+			wantErr:     true,
+		},
+		{
+			name:        "drop",
+			handler:     initHandleConsumerDrop[test, messageAttTest],
+			wantOutcome: HandlerOutcomeDrop,
+			wantErr:     true,
+		},
+		{
+			name:        "dead letter",
+			handler:     initHandleConsumerDeadLetter[test, messageAttTest],
+			opt:         option.NewConsumer().SetDeadLetterQueueUrl("https://sqs.mock/dlq"),
+			wantOutcome: HandlerOutcomeDeadLetter,
+			wantErr:     true,
+		},
+		{
+			name:        "dead letter without queue",
+			handler:     initHandleConsumerDeadLetter[test, messageAttTest],
+			wantOutcome: HandlerOutcomeError,
+			wantErr:     true,
+		},
+		{
+			name:        "ack by context",
+			handler:     initHandleConsumerAck[test, messageAttTest],
+			wantOutcome: HandlerOutcomeAck,
+			wantErr:     true,
+		},
+		{
+			name:        "nack by context",
+			handler:     initHandleConsumerNack[test, messageAttTest],
+			wantOutcome: HandlerOutcomeRetry,
+			wantDelay:   2 * time.Second,
+			wantErr:     true,
+		},
+		{
+			name:        "panic",
+			handler:     initHandleConsumerPanic[test, messageAttTest],
+			wantOutcome: HandlerOutcomePanic,
+			wantErr:     true,
+		},
+		{
+			name:        "timeout",
+			handler:     initHandleConsumerWaitDone[test, messageAttTest],
+			opt:         option.NewConsumer().SetConsumerMessageTimeout(100 * time.Millisecond),
+			wantOutcome: HandlerOutcomeTimeout,
+			wantErr:     true,
+		},
+		{
+			name:    "max receive count exceeded",
+			handler: initHandleConsumer[test, messageAttTest],
+			opt: option.NewConsumer().
+				SetDeleteMessageProcessedSuccess(true).
+				SetQuarantineQueueUrl("https://sqs.mock/quarantine").
+				SetMaxReceiveCount(3),
+			receiveCount: 4,
+			wantOutcome:  HandlerOutcomeQuarantine,
+			wantErr:      true,
+		},
+		{
+			name:    "max receive count not exceeded",
+			handler: initHandleConsumer[test, messageAttTest],
+			opt: option.NewConsumer().
+				SetDeleteMessageProcessedSuccess(true).
+				SetQuarantineQueueUrl("https://sqs.mock/quarantine").
+				SetMaxReceiveCount(3),
+			receiveCount: 3,
+			wantOutcome:  HandlerOutcomeAck,
+		},
+		{
+			name:        "middleware",
+			handler:     initHandleConsumerMiddlewareValue[test, messageAttTest],
+			opt:         option.NewConsumer().SetDeleteMessageProcessedSuccess(true).SetMiddlewares(initMiddlewareValue),
+			wantOutcome: HandlerOutcomeAck,
+		},
+	}
+}
+
+func initListTestReceive() []testReceive {
+	return []testReceive{
+		{
+			name:        "messages",
+			bodies:      []any{initTestStruct(), initTestStruct()},
+			wantSuccess: 2,
+		},
+		{
+			name:        "max number of messages",
+			bodies:      []any{initTestStruct(), initTestStruct()},
+			opt:         option.NewConsumer().SetMaxNumberOfMessages(1),
+			wantSuccess: 1,
+		},
+		{
+			name:        "undecodable message",
+			bodies:      []any{initTestStruct(), `{"name":1}`},
+			opt:         option.NewConsumer().SetStrictBodyDecoding(true),
+			wantSuccess: 1,
+			wantFailed:  1,
+		},
+		{
+			name: "empty queue",
+			opt:  option.NewConsumer().SetWaitTimeSeconds(time.Second),
+		},
+	}
+}
+
 func initListTestCreateQueue() []testCreateQueue {
 	return []testCreateQueue{
 		{
@@ -786,6 +1599,17 @@ func initMessageAttTest() messageAttTest {
 		PointerTest: &t,
 		Map:         initTestMap(),
 		HideString:  "hide test",
+		Bytes:       []byte("bytes test"),
+	}
+}
+
+func initMessageAttTagTest() messageAttTagTest {
+	return messageAttTagTest{
+		Name:    "Name test producer",
+		Balance: 10.23,
+		Payload: []byte("payload test"),
+		Code:    "code test",
+		Hidden:  "hide test",
 	}
 }
 
@@ -836,6 +1660,103 @@ func initSimpleHandleConsumerWithErr[Body any](ctx *SimpleContext[Body]) error {
 	return initErrorConsumer()
 }
 
+func initHandleBatchConsumer[Body, MessageAttributes any](ctx *BatchContext[Body, MessageAttributes]) (
+	[]string, error) {
+	logger.Debug("ctx:", ctx)
+	return nil, nil
+}
+
+func initHandleBatchConsumerPartialFailure[Body, MessageAttributes any](ctx *BatchContext[Body, MessageAttributes]) (
+	[]string, error) {
+	logger.Debug("ctx:", ctx)
+	return []string{ctx.Messages[0].Id}, nil
+}
+
+func initHandleBatchConsumerWithErr[Body, MessageAttributes any](ctx *BatchContext[Body, MessageAttributes]) (
+	[]string, error) {
+	logger.Debug("ctx:", ctx)
+	return nil, initErrorConsumer()
+}
+
+func initHandleConsumerAck[Body, MessageAttributes any](ctx *Context[Body, MessageAttributes]) error {
+	logger.Debug("ctx:", ctx)
+	if err := ctx.ExtendVisibility(10 * time.Second); err != nil {
+		return err
+	} else if err = ctx.Ack(); err != nil {
+		return err
+	}
+	return initErrorConsumer()
+}
+
+func initHandleConsumerNack[Body, MessageAttributes any](ctx *Context[Body, MessageAttributes]) error {
+	logger.Debug("ctx:", ctx)
+	if err := ctx.Nack(2 * time.Second); err != nil {
+		return err
+	}
+	return ctx.Ack()
+}
+
+func initSimpleHandleConsumerAck[Body any](ctx *SimpleContext[Body]) error {
+	logger.Debug("ctx:", ctx)
+	if err := ctx.Ack(); err != nil {
+		return err
+	}
+	return ctx.Nack(time.Second)
+}
+
+func initHandleConsumerRetryAfter[Body, MessageAttributes any](ctx *Context[Body, MessageAttributes]) error {
+	logger.Debug("ctx:", ctx)
+	return RetryAfter(2 * time.Second)
+}
+
+func initHandleConsumerDrop[Body, MessageAttributes any](ctx *Context[Body, MessageAttributes]) error {
+	logger.Debug("ctx:", ctx)
+	return Drop
+}
+
+func initHandleConsumerDeadLetter[Body, MessageAttributes any](ctx *Context[Body, MessageAttributes]) error {
+	logger.Debug("ctx:", ctx)
+	return DeadLetter("test dead letter")
+}
+
+func initHandleConsumerPanic[Body, MessageAttributes any](ctx *Context[Body, MessageAttributes]) error {
+	var m map[string]string
+	m[ctx.Message.Id] = ctx.QueueUrl
+	return nil
+}
+
+func initHandleConsumerWaitDone[Body, MessageAttributes any](ctx *Context[Body, MessageAttributes]) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func initHandleBatchConsumerPanic[Body, MessageAttributes any](ctx *BatchContext[Body, MessageAttributes]) (
+	[]string, error) {
+	var messages []*MessageReceived[Body, MessageAttributes]
+	return []string{messages[len(ctx.Messages)].Id}, nil
+}
+
+func initHandleConsumerMiddlewareValue[Body, MessageAttributes any](ctx *Context[Body, MessageAttributes]) error {
+	if ctx.Value(testMiddlewareKey{}) != "middleware value" {
+		return errors.New("middleware value not found in the context")
+	}
+	return nil
+}
+
+func initMiddlewareValue(next option.MessageHandler) option.MessageHandler {
+	return func(ctx context.Context, queueUrl string, message types.Message) error {
+		return next(context.WithValue(ctx, testMiddlewareKey{}, "middleware value"), queueUrl, message)
+	}
+}
+
+func initOnErrorConsumer(err error) {
+	logger.Error("consumer error:", err)
+}
+
+func initOnPoisonMessageConsumer(queueUrl string, message types.Message, err error) {
+	logger.Error("poison message:", queueUrl, *message.MessageId, err)
+}
+
 func initErrorConsumer() error {
 	return errors.New("test error message")
 }
@@ -904,6 +1825,27 @@ func initOptionsConsumerDefault() []*option.Consumer {
 		option.NewConsumer().SetReceiveRequestAttemptId(""),
 		option.NewConsumer().SetVisibilityTimeout(1 * time.Second),
 		option.NewConsumer().SetWaitTimeSeconds(1 * time.Second),
+		option.NewConsumer().SetMaxInFlightMessages(5),
+		option.NewConsumer().SetPollers(2),
+		option.NewConsumer().SetDrainTimeout(10 * time.Second),
+		option.NewConsumer().SetReceiveErrorPolicy(option.ReceiveErrorPolicy{}),
+		option.NewConsumer().SetOnError(initOnErrorConsumer),
+		option.NewConsumer().SetVisibilityHeartbeat(option.VisibilityHeartbeat{
+			Interval:          time.Second,
+			VisibilityTimeout: 5 * time.Second,
+			MaxExtension:      time.Minute,
+		}),
+		option.NewConsumer().SetDeadLetterQueueUrl(os.Getenv(sqsQueueTestEmptyUrl)),
+		option.NewConsumer().SetRedeliveryBackoff(option.Backoff{
+			Initial: time.Second,
+			Max:     5 * time.Second,
+		}),
+		option.NewConsumer().SetDeleteBuffer(option.DeleteBuffer{
+			Size:          5,
+			FlushInterval: 500 * time.Millisecond,
+		}),
+		option.NewConsumer().SetOnPoisonMessage(initOnPoisonMessageConsumer),
+		option.NewConsumer().SetQuarantineQueueUrl(os.Getenv(sqsQueueTestEmptyUrl)).SetMaxReceiveCount(10),
 	}
 }
 
@@ -919,6 +1861,25 @@ func initOptionsConsumerWithErr() []*option.Consumer {
 		option.NewConsumer().SetReceiveRequestAttemptId("test"),
 		option.NewConsumer().SetVisibilityTimeout(0),
 		option.NewConsumer().SetWaitTimeSeconds(0),
+		option.NewConsumer().SetMaxInFlightMessages(0),
+		option.NewConsumer().SetPollers(0),
+		option.NewConsumer().SetDrainTimeout(0),
+		option.NewConsumer().SetReceiveErrorPolicy(option.ReceiveErrorPolicy{
+			Backoff: option.Backoff{
+				Initial:    100 * time.Millisecond,
+				Max:        time.Second,
+				Multiplier: 3,
+				Jitter:     -1,
+			},
+			MaxAttempts: 3,
+		}),
+		option.NewConsumer().SetOnError(initOnErrorConsumer),
+		option.NewConsumer().SetDeadLetterQueueUrl(""),
+		option.NewConsumer().SetRedeliveryBackoff(option.Backoff{Max: 24 * time.Hour, Jitter: 2}),
+		option.NewConsumer().SetDeleteBuffer(option.DeleteBuffer{Size: 20, MaxAttempts: -1}),
+		option.NewConsumer().SetStrictBodyDecoding(true).SetDisallowUnknownFields(true),
+		option.NewConsumer().SetOnPoisonMessage(initOnPoisonMessageConsumer),
+		option.NewConsumer().SetQuarantineQueueUrl("").SetMaxReceiveCount(-1),
 	}
 }
 
@@ -1029,13 +1990,12 @@ func initMessageReceiptHandle() {
 	initMessageString()
 	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
 	defer cancel()
-	ctxInterrupt = ctx
 	opt := option.NewConsumer().
 		SetDebugMode(true).
 		SetMaxNumberOfMessages(1).
 		SetVisibilityTimeout(0).
 		SetDeleteMessageProcessedSuccess(false)
-	SimpleReceiveMessage[any](os.Getenv(sqsQueueTestStringUrl), func(ctx *SimpleContext[any]) error {
+	_ = SimpleReceiveMessage[any](ctx, os.Getenv(sqsQueueTestStringUrl), func(ctx *SimpleContext[any]) error {
 		_ = os.Setenv(sqsMessageReceiptHandle, ctx.Message.ReceiptHandle)
 		cancel()
 		return nil
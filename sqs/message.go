@@ -2,7 +2,6 @@ package sqs
 
 import (
 	"context"
-	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/client"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/util"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
@@ -122,10 +121,20 @@ type StartMessageMoveTaskInput struct {
 // application is idempotent, so that receiving a message more than once does not
 // cause issues.
 func DeleteMessage(ctx context.Context, queueUrl, receiptHandle string, opts ...*option.Default) (
+	*sqs.DeleteMessageOutput, error) {
+	return defaultClient.DeleteMessage(ctx, queueUrl, receiptHandle, opts...)
+}
+
+// DeleteMessage works like the package function DeleteMessage, using the client c.
+func (c *Client) DeleteMessage(ctx context.Context, queueUrl, receiptHandle string, opts ...*option.Default) (
 	*sqs.DeleteMessageOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "deleting message..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
 		QueueUrl:      &queueUrl,
 		ReceiptHandle: &receiptHandle,
@@ -144,10 +153,20 @@ func DeleteMessage(ctx context.Context, queueUrl, receiptHandle string, opts ...
 // combination of successful and unsuccessful actions, you should check for batch
 // errors even when the call returns an HTTP status code of 200 .
 func DeleteMessageBatch(ctx context.Context, input DeleteMessageBatchInput, opts ...*option.Default) (
+	*sqs.DeleteMessageBatchOutput, error) {
+	return defaultClient.DeleteMessageBatch(ctx, input, opts...)
+}
+
+// DeleteMessageBatch works like the package function DeleteMessageBatch, using the client c.
+func (c *Client) DeleteMessageBatch(ctx context.Context, input DeleteMessageBatchInput, opts ...*option.Default) (
 	*sqs.DeleteMessageBatchOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "deleting messages batch..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
 		Entries:  prepareEntriesDeleteMessageBatch(input.Entries),
 		QueueUrl: &input.QueueUrl,
@@ -203,9 +222,19 @@ func DeleteMessageBatch(ctx context.Context, input DeleteMessageBatchInput, opts
 // ChangeMessageVisibility action) the next time the message is received.
 func ChangeMessageVisibility(ctx context.Context, input ChangeMessageVisibilityInput, opts ...*option.Default) (
 	*sqs.ChangeMessageVisibilityOutput, error) {
+	return defaultClient.ChangeMessageVisibility(ctx, input, opts...)
+}
+
+// ChangeMessageVisibility works like the package function ChangeMessageVisibility, using the client c.
+func (c *Client) ChangeMessageVisibility(ctx context.Context, input ChangeMessageVisibilityInput,
+	opts ...*option.Default) (*sqs.ChangeMessageVisibilityOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "changing messages visibility..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
 		QueueUrl:          &input.QueueUrl,
 		ReceiptHandle:     &input.ReceiptHandle,
@@ -228,9 +257,19 @@ func ChangeMessageVisibility(ctx context.Context, input ChangeMessageVisibilityI
 // 200 .
 func ChangeMessageVisibilityBatch(ctx context.Context, input ChangeMessageVisibilityBatchInput, opts ...*option.Default) (
 	*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	return defaultClient.ChangeMessageVisibilityBatch(ctx, input, opts...)
+}
+
+// ChangeMessageVisibilityBatch works like the package function ChangeMessageVisibilityBatch, using the client c.
+func (c *Client) ChangeMessageVisibilityBatch(ctx context.Context, input ChangeMessageVisibilityBatchInput,
+	opts ...*option.Default) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "changing message visibility batch..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.ChangeMessageVisibilityBatch(ctx, &sqs.ChangeMessageVisibilityBatchInput{
 		Entries:  prepareEntriesChangeMessageVisibilityBatch(input.Entries),
 		QueueUrl: &input.QueueUrl,
@@ -258,10 +297,20 @@ func ChangeMessageVisibilityBatch(ctx context.Context, input ChangeMessageVisibi
 //   - Only one active message movement task is supported per queue at any given
 //     time.
 func StartMessageMoveTask(ctx context.Context, input StartMessageMoveTaskInput, opts ...*option.Default) (
+	*sqs.StartMessageMoveTaskOutput, error) {
+	return defaultClient.StartMessageMoveTask(ctx, input, opts...)
+}
+
+// StartMessageMoveTask works like the package function StartMessageMoveTask, using the client c.
+func (c *Client) StartMessageMoveTask(ctx context.Context, input StartMessageMoveTaskInput, opts ...*option.Default) (
 	*sqs.StartMessageMoveTaskOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "starting message move task..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.StartMessageMoveTask(ctx, &sqs.StartMessageMoveTaskInput{
 		SourceArn:                    &input.SourceArn,
 		DestinationArn:               input.DestinationArn,
@@ -288,10 +337,20 @@ func StartMessageMoveTask(ctx context.Context, input StartMessageMoveTaskInput,
 //   - Only one active message movement task is supported per queue at any given
 //     time.
 func CancelMessageMoveTask(ctx context.Context, taskHandle string, opts ...*option.Default) (
+	*sqs.CancelMessageMoveTaskOutput, error) {
+	return defaultClient.CancelMessageMoveTask(ctx, taskHandle, opts...)
+}
+
+// CancelMessageMoveTask works like the package function CancelMessageMoveTask, using the client c.
+func (c *Client) CancelMessageMoveTask(ctx context.Context, taskHandle string, opts ...*option.Default) (
 	*sqs.CancelMessageMoveTaskOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "canceling message move task..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.CancelMessageMoveTask(ctx, &sqs.CancelMessageMoveTaskInput{
 		TaskHandle: &taskHandle,
 	}, option.FuncByHttpClient(opt.HttpClient))
@@ -314,10 +373,20 @@ func CancelMessageMoveTask(ctx context.Context, taskHandle string, opts ...*opti
 //   - Only one active message movement task is supported per queue at any given
 //     time.
 func ListMessageMoveTasks(ctx context.Context, sourceArn string, opts ...*option.ListMessageMoveTasks) (
+	*sqs.ListMessageMoveTasksOutput, error) {
+	return defaultClient.ListMessageMoveTasks(ctx, sourceArn, opts...)
+}
+
+// ListMessageMoveTasks works like the package function ListMessageMoveTasks, using the client c.
+func (c *Client) ListMessageMoveTasks(ctx context.Context, sourceArn string, opts ...*option.ListMessageMoveTasks) (
 	*sqs.ListMessageMoveTasksOutput, error) {
 	opt := option.GetListMessageMoveTaskByParams(opts)
 	loggerInfo(opt.DebugMode, "listing message move tasks..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.ListMessageMoveTasks(ctx, &sqs.ListMessageMoveTasksInput{
 		SourceArn:  &sourceArn,
 		MaxResults: &opt.MaxResults,
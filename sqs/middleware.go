@@ -0,0 +1,74 @@
+package sqs
+
+import (
+	"context"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var globalMiddlewares []option.Middleware
+var globalMiddlewaresMutex sync.RWMutex
+
+// Use registers middlewares applied to the handler of each message of all consumers started after the call, before
+// the middlewares of option.Consumer.Middlewares. The first middleware is the outermost. They are not applied to the
+// batch consumers, see ReceiveMessageBatch.
+//
+// Example usage:
+//
+//	sqs.Use(sqs.RecoveryMiddleware(), sqs.TimingMiddleware(observe))
+func Use(middlewares ...option.Middleware) {
+	globalMiddlewaresMutex.Lock()
+	defer globalMiddlewaresMutex.Unlock()
+	globalMiddlewares = append(globalMiddlewares, middlewares...)
+}
+
+// RecoveryMiddleware returns a middleware that recovers the panics of the next handlers, returning a *PanicError with
+// the stack trace, so the message fails like any other error. The consumers always recover the panics of the handlers,
+// use it to let the middlewares registered before it observe the panic as an error.
+func RecoveryMiddleware() option.Middleware {
+	return func(next option.MessageHandler) option.MessageHandler {
+		return func(ctx context.Context, queueUrl string, message types.Message) (err error) {
+			defer recoverPanic(&err)
+			return next(ctx, queueUrl, message)
+		}
+	}
+}
+
+// TimingMiddleware returns a middleware that calls observe with the duration and the error of the next handlers for
+// each message, useful to record metrics.
+func TimingMiddleware(
+	observe func(queueUrl string, message types.Message, d time.Duration, err error),
+) option.Middleware {
+	return func(next option.MessageHandler) option.MessageHandler {
+		return func(ctx context.Context, queueUrl string, message types.Message) error {
+			start := time.Now()
+			err := next(ctx, queueUrl, message)
+			observe(queueUrl, message, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// recoverPanic must be called directly by defer, it recovers the panic converting it to a *PanicError in err.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
+
+func getMiddlewares(opt *option.Consumer) []option.Middleware {
+	globalMiddlewaresMutex.RLock()
+	defer globalMiddlewaresMutex.RUnlock()
+	result := make([]option.Middleware, 0, len(globalMiddlewares)+len(opt.Middlewares))
+	return append(append(result, globalMiddlewares...), opt.Middlewares...)
+}
+
+func chainMiddlewares(middlewares []option.Middleware, handler option.MessageHandler) option.MessageHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,94 @@
+package option
+
+import "time"
+
+type AsyncProducer struct {
+	Default
+	// Maximum number of messages of the same queue sent in each batch request. 1 to 10.
+	//
+	// default: 10
+	BatchSize int
+	// Maximum time a message waits in the buffer for the batch of its queue to fill up, when it expires the batch is
+	// sent with the messages buffered so far.
+	//
+	// default: 100 milliseconds
+	Linger time.Duration
+	// Maximum number of messages accepted and not yet delivered, considering all queues, when it is reached Send
+	// blocks until some message is delivered or the context is done.
+	//
+	// default: 1000
+	MaxBufferedMessages int
+	// Maximum time of each batch request, including its attempts (see Producer.BatchMaxAttempts).
+	//
+	// default: 30 seconds
+	SendTimeout time.Duration
+}
+
+func NewAsyncProducer() *AsyncProducer {
+	return &AsyncProducer{}
+}
+
+func (p *AsyncProducer) SetBatchSize(i int) *AsyncProducer {
+	p.BatchSize = i
+	return p
+}
+
+func (p *AsyncProducer) SetLinger(d time.Duration) *AsyncProducer {
+	p.Linger = d
+	return p
+}
+
+func (p *AsyncProducer) SetMaxBufferedMessages(i int) *AsyncProducer {
+	p.MaxBufferedMessages = i
+	return p
+}
+
+func (p *AsyncProducer) SetSendTimeout(d time.Duration) *AsyncProducer {
+	p.SendTimeout = d
+	return p
+}
+
+func (p *AsyncProducer) SetDebugMode(b bool) *AsyncProducer {
+	p.DebugMode = b
+	return p
+}
+
+func (p *AsyncProducer) SetHttpClient(opt HttpClient) *AsyncProducer {
+	p.HttpClient = &opt
+	return p
+}
+
+func GetAsyncProducerByParams(opts []*AsyncProducer) *AsyncProducer {
+	var result AsyncProducer
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		fillDefaultFields(opt.Default, &result.Default)
+		if opt.BatchSize > 0 {
+			result.BatchSize = opt.BatchSize
+		}
+		if opt.Linger > 0 {
+			result.Linger = opt.Linger
+		}
+		if opt.MaxBufferedMessages > 0 {
+			result.MaxBufferedMessages = opt.MaxBufferedMessages
+		}
+		if opt.SendTimeout > 0 {
+			result.SendTimeout = opt.SendTimeout
+		}
+	}
+	if result.BatchSize <= 0 || result.BatchSize > 10 {
+		result.BatchSize = 10
+	}
+	if result.Linger <= 0 {
+		result.Linger = 100 * time.Millisecond
+	}
+	if result.MaxBufferedMessages <= 0 {
+		result.MaxBufferedMessages = 1000
+	}
+	if result.SendTimeout <= 0 {
+		result.SendTimeout = 30 * time.Second
+	}
+	return &result
+}
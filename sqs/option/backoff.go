@@ -0,0 +1,42 @@
+package option
+
+import "time"
+
+type Backoff struct {
+	// Delay applied after the first attempt.
+	Initial time.Duration
+	// Maximum delay, the calculated delay never exceeds this value.
+	Max time.Duration
+	// Factor applied to the delay on each new attempt, e.g. with Initial 1 second and Multiplier 2 the delays
+	// are 1s, 2s, 4s, 8s...
+	//
+	// default: 2
+	Multiplier float64
+	// Random factor between 0 and 1 applied to the delay, avoiding that several consumers try again at the same time,
+	// e.g. with 0.2 a delay of 10 seconds can be anything from 8 to 12 seconds. Use a negative value to disable it.
+	//
+	// default: 0.2
+	Jitter float64
+}
+
+func fillBackoffDefaults(b *Backoff, initial, max time.Duration) {
+	if b.Initial <= 0 {
+		b.Initial = initial
+	}
+	if b.Max <= 0 {
+		b.Max = max
+	}
+	if b.Max < b.Initial {
+		b.Max = b.Initial
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = 2
+	}
+	if b.Jitter == 0 {
+		b.Jitter = 0.2
+	} else if b.Jitter < 0 {
+		b.Jitter = 0
+	} else if b.Jitter > 1 {
+		b.Jitter = 1
+	}
+}
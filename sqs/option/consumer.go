@@ -1,6 +1,10 @@
 package option
 
-import "time"
+import (
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/codec"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"time"
+)
 
 type Consumer struct {
 	Default
@@ -12,13 +16,13 @@ type Consumer struct {
 	//
 	// default: 5 seconds
 	ConsumerMessageTimeout time.Duration
-	// Delay to run the next search for messages in the queue
+	// Delay to run the next search for messages in the queue when the previous search returned no messages
 	//
 	// default: 5 seconds
 	DelayQueryLoop time.Duration
 	// The maximum number of messages to return. Amazon SQS never returns more
 	// messages than this value (however, fewer messages might be returned). Valid
-	// values: 1 to 10.
+	// values: 1 to 10. Each search asks for no more messages than the free workers of MaxInFlightMessages.
 	//
 	// default: 10
 	MaxNumberOfMessages int32
@@ -80,6 +84,139 @@ type Consumer struct {
 	//
 	// default: 0 seconds
 	WaitTimeSeconds time.Duration
+	// Maximum number of messages processed at the same time by the consumer, counting the messages of all pollers,
+	// when all workers are busy, the pollers stop searching for new messages until a worker is released.
+	//
+	// default: MaxNumberOfMessages
+	MaxInFlightMessages int
+	// Number of goroutines searching for messages in the queue at the same time, all sharing the workers limited by
+	// MaxInFlightMessages.
+	//
+	// default: 1
+	Pollers int
+	// Maximum time to wait for the messages in process to finish when the consumer is stopped, after that the
+	// context of the handlers is canceled.
+	//
+	// default: 30 seconds
+	DrainTimeout time.Duration
+	// Policy applied when the consumer fails to receive messages from the queue.
+	//
+	// default: exponential backoff from 1 second up to 1 minute, never gives up
+	ReceiveErrorPolicy *ReceiveErrorPolicy
+	// Function called with every error that occurs in the consumer job, such as failures to receive messages. It can
+	// be called by several goroutines at the same time.
+	OnError func(err error)
+	// If filled, the visibility timeout of the message is extended in the background while the handler is running,
+	// avoiding that the message becomes visible again and is processed twice. The extensions stop as soon as the
+	// handler returns or the ConsumerMessageTimeout is reached.
+	//
+	// default: nil (disabled)
+	VisibilityHeartbeat *VisibilityHeartbeat
+	// Url of the queue where the messages are sent when the handler returns sqs.DeadLetter.
+	//
+	// default: nil
+	DeadLetterQueueUrl *string
+	// If filled, when the handler returns an error the visibility timeout of the message is changed to a delay
+	// calculated by the ApproximateReceiveCount, so the messages that keep failing are retried less often, Initial
+	// default is 30 seconds and Max default is 15 minutes (maximum of 12 hours). The delay is rounded up to whole
+	// seconds, the unit of the visibility timeout. Errors returned by sqs.RetryAfter, sqs.Drop and sqs.DeadLetter are
+	// not affected.
+	//
+	// default: nil (disabled)
+	RedeliveryBackoff *Backoff
+	// Buffer of the messages to be removed from the queue by the consumer, they are removed in groups with
+	// DeleteMessageBatch when the buffer is full or the flush interval is reached, the failures by a server error, or
+	// by a request error classified as retryable by the AWS SDK, are retried, and the failures are reported to OnError.
+	//
+	// default: groups of 10 messages, flush every 1 second, 3 attempts with backoff from 200 milliseconds up to
+	// 2 seconds
+	DeleteBuffer *DeleteBuffer
+	// Codec used to convert the body of the messages without the codec.ContentTypeAttributeName message attribute,
+	// the messages with this attribute are converted by the codec registered with its content type (see
+	// codec.Register).
+	//
+	// default: nil (the body is converted by type, trying JSON, int, bool, float, time and string)
+	Codec codec.Codec
+	// If true, the body without codec, or with the codec.JSON, is decoded strictly: zero values like 0, false, "" or
+	// an empty struct are accepted, and the errors of encoding/json, with the path of the field, are returned instead of
+	// trying other types. Bodies of type string receive the body as is.
+	//
+	// default: false
+	StrictBodyDecoding bool
+	// If true, together with StrictBodyDecoding, the JSON bodies with fields that are not in the Body type are
+	// rejected.
+	//
+	// default: false
+	DisallowUnknownFields bool
+	// Function called with the messages that cannot be converted, by the body or the message attributes, and the
+	// error of the conversion. These messages are not passed to the handler and are left in the queue. It can be
+	// called by several goroutines at the same time.
+	//
+	// default: nil (the error is only logged in debug mode)
+	OnPoisonMessage func(queueUrl string, message types.Message, err error)
+	// Url of the queue where the messages that cannot be converted, or that exceeded the MaxReceiveCount, are sent
+	// with the original body and message attributes, plus the error in the sqs.FailureReasonAttributeName attribute
+	// and the source queue url in the sqs.SourceQueueUrlAttributeName attribute, while they fit in the
+	// sqs.MaxMessageAttributes, then they are removed from the queue. The error and the url that do not fit are
+	// reported to OnError with sqs.ErrForwardAttributesDropped.
+	//
+	// default: nil (the messages are left in the queue)
+	QuarantineQueueUrl *string
+	// Maximum number of times a message can be received, by its ApproximateReceiveCount, before it is sent to the
+	// QuarantineQueueUrl without being passed to the handler. It only applies if QuarantineQueueUrl is filled.
+	//
+	// default: 0 (disabled)
+	MaxReceiveCount int
+	// Middlewares that wrap the handler of each message, after the global middlewares registered with sqs.Use, the
+	// first one is the outermost. They are not applied to the batch consumers.
+	//
+	// default: nil
+	Middlewares []Middleware
+}
+
+type DeleteBuffer struct {
+	// Maximum number of messages in each DeleteMessageBatch call. 1 to 10.
+	//
+	// default: 10
+	Size int
+	// Maximum time that a processed message waits in the buffer before being removed.
+	//
+	// default: 1 second
+	FlushInterval time.Duration
+	// Maximum number of attempts to remove a message before reporting the failure.
+	//
+	// default: 3
+	MaxAttempts int
+	// Delay between the attempts to remove the messages, Initial default is 200 milliseconds and Max default is
+	// 2 seconds.
+	Backoff
+}
+
+type VisibilityHeartbeat struct {
+	// Interval between the extensions of the message visibility timeout.
+	//
+	// default: 10 seconds
+	Interval time.Duration
+	// Visibility timeout applied on each extension, counted from the moment of the extension, it must be greater
+	// than the Interval.
+	//
+	// default: 2 times the Interval
+	VisibilityTimeout time.Duration
+	// Maximum total time that the visibility can be extended, counted from the start of the processing, after that
+	// the extensions are stopped.
+	//
+	// default: 12 hours (maximum allowed by AWS SQS)
+	MaxExtension time.Duration
+}
+
+type ReceiveErrorPolicy struct {
+	// Delay between the failed attempts to receive messages, Initial default is 1 second and Max default is 1 minute.
+	Backoff
+	// Maximum number of consecutive failed attempts before the consumer gives up and stops returning the error, the
+	// counter is reset after every successful attempt. 0 means that the consumer never gives up.
+	//
+	// default: 0
+	MaxAttempts int
 }
 
 func NewConsumer() *Consumer {
@@ -121,6 +258,86 @@ func (o *Consumer) SetWaitTimeSeconds(d time.Duration) *Consumer {
 	return o
 }
 
+func (o *Consumer) SetMaxInFlightMessages(i int) *Consumer {
+	o.MaxInFlightMessages = i
+	return o
+}
+
+func (o *Consumer) SetPollers(i int) *Consumer {
+	o.Pollers = i
+	return o
+}
+
+func (o *Consumer) SetDrainTimeout(d time.Duration) *Consumer {
+	o.DrainTimeout = d
+	return o
+}
+
+func (o *Consumer) SetReceiveErrorPolicy(p ReceiveErrorPolicy) *Consumer {
+	o.ReceiveErrorPolicy = &p
+	return o
+}
+
+func (o *Consumer) SetOnError(f func(err error)) *Consumer {
+	o.OnError = f
+	return o
+}
+
+func (o *Consumer) SetVisibilityHeartbeat(h VisibilityHeartbeat) *Consumer {
+	o.VisibilityHeartbeat = &h
+	return o
+}
+
+func (o *Consumer) SetDeadLetterQueueUrl(s string) *Consumer {
+	o.DeadLetterQueueUrl = &s
+	return o
+}
+
+func (o *Consumer) SetRedeliveryBackoff(b Backoff) *Consumer {
+	o.RedeliveryBackoff = &b
+	return o
+}
+
+func (o *Consumer) SetDeleteBuffer(b DeleteBuffer) *Consumer {
+	o.DeleteBuffer = &b
+	return o
+}
+
+func (o *Consumer) SetCodec(c codec.Codec) *Consumer {
+	o.Codec = c
+	return o
+}
+
+func (o *Consumer) SetStrictBodyDecoding(b bool) *Consumer {
+	o.StrictBodyDecoding = b
+	return o
+}
+
+func (o *Consumer) SetDisallowUnknownFields(b bool) *Consumer {
+	o.DisallowUnknownFields = b
+	return o
+}
+
+func (o *Consumer) SetOnPoisonMessage(f func(queueUrl string, message types.Message, err error)) *Consumer {
+	o.OnPoisonMessage = f
+	return o
+}
+
+func (o *Consumer) SetQuarantineQueueUrl(s string) *Consumer {
+	o.QuarantineQueueUrl = &s
+	return o
+}
+
+func (o *Consumer) SetMaxReceiveCount(i int) *Consumer {
+	o.MaxReceiveCount = i
+	return o
+}
+
+func (o *Consumer) SetMiddlewares(m ...Middleware) *Consumer {
+	o.Middlewares = m
+	return o
+}
+
 func (o *Consumer) SetDebugMode(b bool) *Consumer {
 	o.DebugMode = b
 	return o
@@ -159,6 +376,54 @@ func GetConsumerByParams(opts []*Consumer) *Consumer {
 		if opt.WaitTimeSeconds > 0 {
 			result.WaitTimeSeconds = opt.WaitTimeSeconds
 		}
+		if opt.MaxInFlightMessages > 0 {
+			result.MaxInFlightMessages = opt.MaxInFlightMessages
+		}
+		if opt.Pollers > 0 {
+			result.Pollers = opt.Pollers
+		}
+		if opt.DrainTimeout > 0 {
+			result.DrainTimeout = opt.DrainTimeout
+		}
+		if opt.ReceiveErrorPolicy != nil {
+			result.ReceiveErrorPolicy = opt.ReceiveErrorPolicy
+		}
+		if opt.OnError != nil {
+			result.OnError = opt.OnError
+		}
+		if opt.VisibilityHeartbeat != nil {
+			result.VisibilityHeartbeat = opt.VisibilityHeartbeat
+		}
+		if opt.DeadLetterQueueUrl != nil && len(*opt.DeadLetterQueueUrl) != 0 {
+			result.DeadLetterQueueUrl = opt.DeadLetterQueueUrl
+		}
+		if opt.RedeliveryBackoff != nil {
+			result.RedeliveryBackoff = opt.RedeliveryBackoff
+		}
+		if opt.DeleteBuffer != nil {
+			result.DeleteBuffer = opt.DeleteBuffer
+		}
+		if opt.Codec != nil {
+			result.Codec = opt.Codec
+		}
+		if opt.StrictBodyDecoding {
+			result.StrictBodyDecoding = opt.StrictBodyDecoding
+		}
+		if opt.DisallowUnknownFields {
+			result.DisallowUnknownFields = opt.DisallowUnknownFields
+		}
+		if opt.OnPoisonMessage != nil {
+			result.OnPoisonMessage = opt.OnPoisonMessage
+		}
+		if opt.QuarantineQueueUrl != nil && len(*opt.QuarantineQueueUrl) != 0 {
+			result.QuarantineQueueUrl = opt.QuarantineQueueUrl
+		}
+		if opt.MaxReceiveCount > 0 {
+			result.MaxReceiveCount = opt.MaxReceiveCount
+		}
+		if len(opt.Middlewares) != 0 {
+			result.Middlewares = append(result.Middlewares, opt.Middlewares...)
+		}
 	}
 	if result.MaxNumberOfMessages <= 0 {
 		result.MaxNumberOfMessages = 10
@@ -169,5 +434,64 @@ func GetConsumerByParams(opts []*Consumer) *Consumer {
 	if result.DelayQueryLoop.Seconds() == 0 {
 		result.DelayQueryLoop = 5 * time.Second
 	}
+	if result.MaxInFlightMessages <= 0 {
+		result.MaxInFlightMessages = int(result.MaxNumberOfMessages)
+	}
+	if result.Pollers <= 0 {
+		result.Pollers = 1
+	}
+	if result.DrainTimeout.Seconds() == 0 {
+		result.DrainTimeout = 30 * time.Second
+	}
+	receiveErrorPolicy := ReceiveErrorPolicy{}
+	if result.ReceiveErrorPolicy != nil {
+		receiveErrorPolicy = *result.ReceiveErrorPolicy
+	}
+	fillBackoffDefaults(&receiveErrorPolicy.Backoff, time.Second, time.Minute)
+	result.ReceiveErrorPolicy = &receiveErrorPolicy
+	if result.VisibilityHeartbeat != nil {
+		visibilityHeartbeat := *result.VisibilityHeartbeat
+		fillVisibilityHeartbeatDefaults(&visibilityHeartbeat)
+		result.VisibilityHeartbeat = &visibilityHeartbeat
+	}
+	if result.RedeliveryBackoff != nil {
+		redeliveryBackoff := *result.RedeliveryBackoff
+		fillBackoffDefaults(&redeliveryBackoff, 30*time.Second, 15*time.Minute)
+		if redeliveryBackoff.Max > 12*time.Hour {
+			redeliveryBackoff.Max = 12 * time.Hour
+		}
+		result.RedeliveryBackoff = &redeliveryBackoff
+	}
+	deleteBuffer := DeleteBuffer{}
+	if result.DeleteBuffer != nil {
+		deleteBuffer = *result.DeleteBuffer
+	}
+	fillDeleteBufferDefaults(&deleteBuffer)
+	result.DeleteBuffer = &deleteBuffer
 	return &result
 }
+
+func fillDeleteBufferDefaults(b *DeleteBuffer) {
+	if b.Size <= 0 || b.Size > 10 {
+		b.Size = 10
+	}
+	if b.FlushInterval <= 0 {
+		b.FlushInterval = time.Second
+	}
+	if b.MaxAttempts <= 0 {
+		b.MaxAttempts = 3
+	}
+	fillBackoffDefaults(&b.Backoff, 200*time.Millisecond, 2*time.Second)
+}
+
+func fillVisibilityHeartbeatDefaults(h *VisibilityHeartbeat) {
+	if h.Interval <= 0 {
+		h.Interval = 10 * time.Second
+	}
+	if h.VisibilityTimeout <= h.Interval {
+		h.VisibilityTimeout = 2 * h.Interval
+	}
+	if h.MaxExtension <= 0 || h.MaxExtension > 12*time.Hour {
+		h.MaxExtension = 12 * time.Hour
+	}
+}
@@ -79,31 +79,88 @@ type HttpClient struct {
 	AuthSchemes []smithyhttp.AuthScheme
 }
 
+// FuncByHttpClient returns the function applied to the AWS SQS client options on each operation call, only the
+// non-zero fields of opt are merged into the options, APIOptions are appended to the client ones.
 func FuncByHttpClient(opt *HttpClient) func(options *sqs.Options) {
 	return func(options *sqs.Options) {
-		if opt == nil {
+		if opt == nil || options == nil {
 			return
 		}
-		options = &sqs.Options{
-			APIOptions:                       opt.APIOptions,
-			AppID:                            opt.AppID,
-			BaseEndpoint:                     opt.BaseEndpoint,
-			ClientLogMode:                    opt.ClientLogMode,
-			Credentials:                      opt.Credentials,
-			DefaultsMode:                     opt.DefaultsMode,
-			DisableMessageChecksumValidation: opt.DisableMessageChecksumValidation,
-			EndpointOptions:                  opt.EndpointOptions,
-			EndpointResolverV2:               opt.EndpointResolverV2,
-			HTTPSignerV4:                     opt.HTTPSignerV4,
-			Logger:                           opt.Logger,
-			Region:                           opt.Region,
-			RetryMaxAttempts:                 opt.RetryMaxAttempts,
-			RetryMode:                        opt.RetryMode,
-			Retryer:                          opt.Retryer,
-			RuntimeEnvironment:               opt.RuntimeEnvironment,
-			HTTPClient:                       opt.HTTPClient,
-			AuthSchemeResolver:               opt.AuthSchemeResolver,
-			AuthSchemes:                      opt.AuthSchemes,
+		if len(opt.APIOptions) != 0 {
+			options.APIOptions = append(options.APIOptions, opt.APIOptions...)
 		}
+		if len(opt.AppID) != 0 {
+			options.AppID = opt.AppID
+		}
+		if opt.BaseEndpoint != nil {
+			options.BaseEndpoint = opt.BaseEndpoint
+		}
+		if opt.ClientLogMode != 0 {
+			options.ClientLogMode = opt.ClientLogMode
+		}
+		if opt.Credentials != nil {
+			options.Credentials = opt.Credentials
+		}
+		if len(opt.DefaultsMode) != 0 {
+			options.DefaultsMode = opt.DefaultsMode
+		}
+		if opt.DisableMessageChecksumValidation {
+			options.DisableMessageChecksumValidation = true
+		}
+		mergeEndpointOptions(opt.EndpointOptions, &options.EndpointOptions)
+		if opt.EndpointResolverV2 != nil {
+			options.EndpointResolverV2 = opt.EndpointResolverV2
+		}
+		if opt.HTTPSignerV4 != nil {
+			options.HTTPSignerV4 = opt.HTTPSignerV4
+		}
+		if opt.Logger != nil {
+			options.Logger = opt.Logger
+		}
+		if len(opt.Region) != 0 {
+			options.Region = opt.Region
+		}
+		if opt.RetryMaxAttempts != 0 {
+			options.RetryMaxAttempts = opt.RetryMaxAttempts
+		}
+		if len(opt.RetryMode) != 0 {
+			options.RetryMode = opt.RetryMode
+		}
+		if opt.Retryer != nil {
+			options.Retryer = opt.Retryer
+		}
+		if opt.RuntimeEnvironment != (aws.RuntimeEnvironment{}) {
+			options.RuntimeEnvironment = opt.RuntimeEnvironment
+		}
+		if opt.HTTPClient != nil {
+			options.HTTPClient = opt.HTTPClient
+		}
+		if opt.AuthSchemeResolver != nil {
+			options.AuthSchemeResolver = opt.AuthSchemeResolver
+		}
+		if len(opt.AuthSchemes) != 0 {
+			options.AuthSchemes = opt.AuthSchemes
+		}
+	}
+}
+
+func mergeEndpointOptions(opt sqs.EndpointResolverOptions, dest *sqs.EndpointResolverOptions) {
+	if opt.Logger != nil {
+		dest.Logger = opt.Logger
+	}
+	if opt.LogDeprecated {
+		dest.LogDeprecated = true
+	}
+	if len(opt.ResolvedRegion) != 0 {
+		dest.ResolvedRegion = opt.ResolvedRegion
+	}
+	if opt.DisableHTTPS {
+		dest.DisableHTTPS = true
+	}
+	if opt.UseDualStackEndpoint != aws.DualStackEndpointStateUnset {
+		dest.UseDualStackEndpoint = opt.UseDualStackEndpoint
+	}
+	if opt.UseFIPSEndpoint != aws.FIPSEndpointStateUnset {
+		dest.UseFIPSEndpoint = opt.UseFIPSEndpoint
 	}
 }
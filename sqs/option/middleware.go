@@ -0,0 +1,15 @@
+package option
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// MessageHandler is the non-generic view of a consumer handler used by the middlewares, it receives the context of
+// the handler, the queue url and the raw message received from AWS SQS. The context passed to next is the context
+// received by the consumer handler, so a middleware can add values to it, such as the tenant or the trace.
+type MessageHandler func(ctx context.Context, queueUrl string, message types.Message) error
+
+// Middleware wraps a MessageHandler with cross-cutting behavior, such as logging, metrics, tracing or auth checks,
+// it must call next to continue the processing, or return an error to fail the message without calling the handler.
+type Middleware func(next MessageHandler) MessageHandler
@@ -2,6 +2,7 @@ package option
 
 import (
 	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/util"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/codec"
 	"reflect"
 	"time"
 )
@@ -78,6 +79,16 @@ type Producer struct {
 	// in the Amazon SQS Developer Guide. MessageGroupId is required for FIFO queues.
 	// You can't use it for Standard queues.
 	MessageGroupId *string `json:"messageGroupId,omitempty"`
+	// Maximum number of attempts to send the entries of SendMessageBatch that failed by a server error, or whose request
+	// failed by an error classified as retryable by the AWS SDK.
+	//
+	// default: 3
+	BatchMaxAttempts int `json:"batchMaxAttempts,omitempty"`
+	// Codec used to convert the body, its content type is sent in the codec.ContentTypeAttributeName message attribute
+	// so the consumer can convert the body with the same codec.
+	//
+	// default: nil (the body is converted by type, structs, maps and slices as JSON)
+	Codec codec.Codec `json:"-"`
 }
 
 type MessageSystemAttributes struct {
@@ -117,6 +128,16 @@ func (p *Producer) SetMessageGroupId(s string) *Producer {
 	return p
 }
 
+func (p *Producer) SetBatchMaxAttempts(i int) *Producer {
+	p.BatchMaxAttempts = i
+	return p
+}
+
+func (p *Producer) SetCodec(c codec.Codec) *Producer {
+	p.Codec = c
+	return p
+}
+
 func (p *Producer) SetDebugMode(b bool) *Producer {
 	p.DebugMode = b
 	return p
@@ -151,6 +172,15 @@ func GetProducerByParams(opts []*Producer) *Producer {
 		if opt.MessageGroupId != nil && len(*opt.MessageGroupId) != 0 {
 			result.MessageGroupId = opt.MessageGroupId
 		}
+		if opt.BatchMaxAttempts > 0 {
+			result.BatchMaxAttempts = opt.BatchMaxAttempts
+		}
+		if opt.Codec != nil {
+			result.Codec = opt.Codec
+		}
+	}
+	if result.BatchMaxAttempts <= 0 {
+		result.BatchMaxAttempts = 3
 	}
 	return &result
 }
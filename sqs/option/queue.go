@@ -1,5 +1,7 @@
 package option
 
+import "github.com/GabrielHCataldo/go-aws-sqs-template/sqs/codec"
+
 type CreateQueue struct {
 	Default
 	// A map of attributes with their corresponding values. The following lists the
@@ -168,6 +170,16 @@ type ListDeadLetterSourceQueues struct {
 	NextToken *string
 }
 
+type Queue struct {
+	// Codec used to encode the body of the messages sent and to decode the body of the messages received by the
+	// queue, the Producer.Codec and Consumer.Codec have priority over it.
+	Codec codec.Codec `json:"-"`
+	// Options applied to all messages sent by the queue, before the options passed to each call.
+	Producer *Producer `json:"producer,omitempty"`
+	// Options applied to all consumers and receives of the queue, before the options passed to each call.
+	Consumer *Consumer `json:"consumer,omitempty"`
+}
+
 func NewCreateQueue() *CreateQueue {
 	return &CreateQueue{}
 }
@@ -180,6 +192,10 @@ func NewListDeadLetterSourceQueues() *ListDeadLetterSourceQueues {
 	return &ListDeadLetterSourceQueues{}
 }
 
+func NewQueue() *Queue {
+	return &Queue{}
+}
+
 func (c *CreateQueue) SetAttributes(m map[string]string) *CreateQueue {
 	c.Attributes = m
 	return c
@@ -245,6 +261,21 @@ func (l *ListDeadLetterSourceQueues) SetNextToken(nextToken string) *ListDeadLet
 	return l
 }
 
+func (q *Queue) SetCodec(c codec.Codec) *Queue {
+	q.Codec = c
+	return q
+}
+
+func (q *Queue) SetProducer(p *Producer) *Queue {
+	q.Producer = p
+	return q
+}
+
+func (q *Queue) SetConsumer(c *Consumer) *Queue {
+	q.Consumer = c
+	return q
+}
+
 func GetCreateQueueByParams(opts []*CreateQueue) *CreateQueue {
 	var result CreateQueue
 	for _, opt := range opts {
@@ -301,3 +332,22 @@ func GetListDeadLetterSourceQueuesByParams(opts []*ListDeadLetterSourceQueues) *
 	}
 	return &result
 }
+
+func GetQueueByParams(opts []*Queue) *Queue {
+	var result Queue
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Codec != nil {
+			result.Codec = opt.Codec
+		}
+		if opt.Producer != nil {
+			result.Producer = opt.Producer
+		}
+		if opt.Consumer != nil {
+			result.Consumer = opt.Consumer
+		}
+	}
+	return &result
+}
@@ -3,8 +3,9 @@ package sqs
 import (
 	"context"
 	"errors"
-	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/client"
+	"fmt"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/util"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/codec"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
@@ -36,9 +37,19 @@ import (
 // - *sqs.SendMessageOutput: The result of the SendMessage operation.
 // - error: An error if one occurs during the SendMessage operation.
 func SendMessage(ctx context.Context, queueUrl string, body any, opts ...*option.Producer) (*sqs.SendMessageOutput, error) {
+	return defaultClient.SendMessage(ctx, queueUrl, body, opts...)
+}
+
+// SendMessage works like the package function SendMessage, using the client c.
+func (c *Client) SendMessage(ctx context.Context, queueUrl string, body any, opts ...*option.Producer) (
+	*sqs.SendMessageOutput, error) {
 	opt := option.GetProducerByParams(opts)
 	loggerInfo(opt.DebugMode, "getting client sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	loggerInfo(opt.DebugMode, "preparing message input..")
 	input, err := prepareMessageInput(queueUrl, body, opt)
 	if err != nil {
@@ -74,22 +85,38 @@ func SendMessage(ctx context.Context, queueUrl string, body any, opts ...*option
 //
 // SendMessageAsync(ctx, queueUrl, v, opts...)
 func SendMessageAsync(ctx context.Context, queueUrl string, body any, opts ...*option.Producer) {
-	go sendMessageAsync(ctx, queueUrl, body, opts...)
+	defaultClient.SendMessageAsync(ctx, queueUrl, body, opts...)
 }
 
-func sendMessageAsync(ctx context.Context, queueUrl string, body any, opts ...*option.Producer) {
-	_, _ = SendMessage(ctx, queueUrl, body, opts...)
+// SendMessageAsync works like the package function SendMessageAsync, using the client c.
+func (c *Client) SendMessageAsync(ctx context.Context, queueUrl string, body any, opts ...*option.Producer) {
+	go c.sendMessageAsync(ctx, queueUrl, body, opts...)
+}
+
+func (c *Client) sendMessageAsync(ctx context.Context, queueUrl string, body any, opts ...*option.Producer) {
+	_, _ = c.SendMessage(ctx, queueUrl, body, opts...)
 }
 
 func prepareMessageInput(queueUrl string, v any, opt *option.Producer) (*sqs.SendMessageInput, error) {
-	body := util.ConvertToString(v)
-	if len(body) == 0 {
+	body, err := encodeBody(v, opt)
+	if err != nil {
+		return nil, err
+	} else if len(body) == 0 {
 		return nil, ErrMessageBodyEmpty
 	}
 	messageAttByOpt, err := getMessageAttValueByOpt(opt)
 	if err != nil {
 		return nil, err
 	}
+	if opt.Codec != nil {
+		if messageAttByOpt == nil {
+			messageAttByOpt = map[string]types.MessageAttributeValue{}
+		}
+		messageAttByOpt[codec.ContentTypeAttributeName] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(opt.Codec.ContentType()),
+		}
+	}
 	return &sqs.SendMessageInput{
 		MessageBody:             aws.String(body),
 		QueueUrl:                &queueUrl,
@@ -101,6 +128,19 @@ func prepareMessageInput(queueUrl string, v any, opt *option.Producer) (*sqs.Sen
 	}, nil
 }
 
+func encodeBody(v any, opt *option.Producer) (string, error) {
+	if opt.Codec == nil {
+		return util.ConvertToString(v), nil
+	} else if !util.IsValidType(v) {
+		return "", nil
+	}
+	body, err := opt.Codec.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrEncodeBody, err)
+	}
+	return body, nil
+}
+
 func getMessageAttValueByOpt(opt *option.Producer) (map[string]types.MessageAttributeValue, error) {
 	if opt.MessageAttributes == nil {
 		return nil, nil
@@ -166,6 +206,13 @@ func convertStructToMessageAttValue(t reflect.Type, v reflect.Value) (map[string
 	for i := 0; i < v.NumField(); i++ {
 		fieldValue := v.Field(i)
 		fieldStruct := t.Field(i)
+		if tag, ok := fieldStruct.Tag.Lookup("sqs"); ok {
+			err := convertFieldBySqsTag(fieldStruct, fieldValue, util.GetSqsTag(tag), result)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
 		fieldName := util.GetJsonNameByTag(fieldStruct.Tag.Get("json"))
 		if fieldName == "-" {
 			continue
@@ -183,13 +230,70 @@ func convertStructToMessageAttValue(t reflect.Type, v reflect.Value) (map[string
 	return result, nil
 }
 
+func convertFieldBySqsTag(
+	fieldStruct reflect.StructField,
+	fieldValue reflect.Value,
+	tag util.SqsTag,
+	result map[string]types.MessageAttributeValue,
+) error {
+	if tag.Name == "-" || !fieldValue.CanInterface() {
+		return nil
+	} else if len(tag.Name) == 0 {
+		tag.Name = fieldStruct.Name
+	}
+	if fieldValue.Kind() == reflect.Pointer || fieldValue.Kind() == reflect.Interface {
+		fieldValue = fieldValue.Elem()
+	}
+	if util.IsZeroReflect(fieldValue) {
+		if tag.Required {
+			return fmt.Errorf("%w: %s", ErrMessageAttributeRequired, tag.Name)
+		} else if tag.OmitEmpty || fieldValue.Kind() == reflect.Invalid {
+			return nil
+		}
+	}
+	dataType := tag.DataType
+	if len(dataType) == 0 {
+		dataType = util.GetDataType(fieldValue.Interface())
+	}
+	valueConverted := &types.MessageAttributeValue{DataType: &dataType}
+	switch util.GetBaseDataType(dataType) {
+	case "Binary":
+		if util.IsBytesType(fieldValue.Type()) {
+			valueConverted.BinaryValue = util.ConvertToBytes(fieldValue)
+		} else {
+			valueConverted.BinaryValue = []byte(util.ConvertToString(fieldValue.Interface()))
+		}
+	case "String", "Number":
+		strValue := util.ConvertToString(fieldValue.Interface())
+		if len(strValue) == 0 && (fieldValue.Kind() == reflect.Bool ||
+			util.GetDataType(fieldValue.Interface()) == "Number") {
+			strValue = fmt.Sprint(fieldValue.Interface())
+		}
+		valueConverted.StringValue = &strValue
+	default:
+		return fmt.Errorf("%w: %s %s", ErrMessageAttributeDataType, tag.Name, dataType)
+	}
+	if util.IsNonZeroMessageAttValue(valueConverted) {
+		result[tag.Name] = *valueConverted
+	}
+	return nil
+}
+
 func convertReflectToMessageAttributeValue(v reflect.Value) *types.MessageAttributeValue {
 	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
 		v = v.Elem()
 	}
+	if messageAttValue, ok := v.Interface().(types.MessageAttributeValue); ok {
+		return &messageAttValue
+	}
 	var result *types.MessageAttributeValue
 	dataType := util.GetDataType(v.Interface())
-	if dataType == "String" || dataType == "Number" {
+	if dataType == "Binary" {
+		result = &types.MessageAttributeValue{
+			DataType:    &dataType,
+			BinaryValue: util.ConvertToBytes(v),
+		}
+	} else if dataType == "String" || dataType == "Number" {
 		result = &types.MessageAttributeValue{}
 		result.DataType = &dataType
 		strValue := util.ConvertToString(v.Interface())
@@ -0,0 +1,195 @@
+package sqs
+
+import (
+	"context"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"sync"
+	"time"
+)
+
+// AsyncProducer is a long-lived producer that buffers the messages by queue URL and sends them with SendMessageBatch
+// when the batch of the queue fills up (option.AsyncProducer.BatchSize) or when the first buffered message waited
+// option.AsyncProducer.Linger, the result of each message is reported to the delivery callback.
+//
+// The number of accepted messages not yet delivered is bounded by option.AsyncProducer.MaxBufferedMessages, call
+// Close before exiting so that the buffered messages are not lost.
+type AsyncProducer struct {
+	client     *Client
+	opt        *option.AsyncProducer
+	onDelivery func(delivery *Delivery)
+	slots      chan struct{}
+	mutex      sync.Mutex
+	batches    map[string]*asyncBatch
+	inFlight   int
+	idle       chan struct{}
+	closed     bool
+}
+
+// Delivery represents the result of a message sent by AsyncProducer.
+type Delivery struct {
+	// queue url where the message was sent
+	QueueUrl string
+	// body passed to AsyncProducer.Send
+	Body any
+	// An identifier for the message, filled if the message was sent successfully.
+	MessageId string
+	// This parameter applies only to FIFO (first-in-first-out) queues. The large,
+	// non-consecutive number that Amazon SQS assigns to each message.
+	SequenceNumber string
+	// Error that occurred when preparing or sending the message, nil if it was sent successfully.
+	Err error
+}
+
+type asyncBatch struct {
+	entries []SendMessageBatchEntry
+	timer   *time.Timer
+}
+
+// NewAsyncProducer creates an AsyncProducer using the default client, see Client.NewAsyncProducer.
+func NewAsyncProducer(onDelivery func(delivery *Delivery), opts ...*option.AsyncProducer) *AsyncProducer {
+	return defaultClient.NewAsyncProducer(onDelivery, opts...)
+}
+
+// NewAsyncProducer creates an AsyncProducer using the client c.
+//
+// # Parameters
+//
+// - onDelivery: function called with the result of each message, it's called by the goroutine that sent the batch,
+// so it must not block for a long time. May be nil.
+// - opts: list of option.AsyncProducer to customize the producer
+//
+// # Returns
+//
+// - *AsyncProducer: the producer ready to receive messages with Send
+func (c *Client) NewAsyncProducer(onDelivery func(delivery *Delivery), opts ...*option.AsyncProducer) *AsyncProducer {
+	opt := option.GetAsyncProducerByParams(opts)
+	return &AsyncProducer{
+		client:     getClient(c),
+		opt:        opt,
+		onDelivery: onDelivery,
+		slots:      make(chan struct{}, opt.MaxBufferedMessages),
+		batches:    map[string]*asyncBatch{},
+	}
+}
+
+// Send adds the message to the buffer of the queue, the message is converted and sent later, and its result is
+// reported to the delivery callback. If the buffer is full, Send blocks until some message is delivered.
+//
+// # Parameters
+//
+// - ctx: context used only while waiting for space in the buffer
+// - queueUrl: url of the queue where the message is sent
+// - body: the content of the message, converted the same way as in SendMessage
+// - opts: list of option.Producer to customize the message
+//
+// # Returns
+//
+// - error: ErrAsyncProducerClosed if Close was called, or the ctx error if it was done while waiting for space in
+// the buffer
+func (p *AsyncProducer) Send(ctx context.Context, queueUrl string, body any, opts ...*option.Producer) error {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		<-p.slots
+		return ErrAsyncProducerClosed
+	}
+	if p.inFlight == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.inFlight++
+	batch, ok := p.batches[queueUrl]
+	if !ok {
+		batch = &asyncBatch{}
+		p.batches[queueUrl] = batch
+		batch.timer = time.AfterFunc(p.opt.Linger, func() {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			if p.batches[queueUrl] == batch {
+				p.dispatch(queueUrl, batch)
+			}
+		})
+	}
+	batch.entries = append(batch.entries, SendMessageBatchEntry{Body: body, Opts: opts})
+	if len(batch.entries) >= p.opt.BatchSize {
+		p.dispatch(queueUrl, batch)
+	}
+	return nil
+}
+
+// Flush sends all buffered messages without waiting for the linger and waits until all accepted messages are
+// delivered, including those accepted while waiting.
+//
+// # Returns
+//
+// - error: the ctx error if it was done before all messages were delivered
+func (p *AsyncProducer) Flush(ctx context.Context) error {
+	p.mutex.Lock()
+	for queueUrl, batch := range p.batches {
+		p.dispatch(queueUrl, batch)
+	}
+	if p.inFlight == 0 {
+		p.mutex.Unlock()
+		return nil
+	}
+	idle := p.idle
+	p.mutex.Unlock()
+	loggerInfo(p.opt.DebugMode, "waiting for buffered messages to be delivered..")
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting new messages, after it Send returns ErrAsyncProducerClosed, and flushes the buffered messages
+// like Flush.
+//
+// # Returns
+//
+// - error: the ctx error if it was done before all messages were delivered
+func (p *AsyncProducer) Close(ctx context.Context) error {
+	p.mutex.Lock()
+	p.closed = true
+	p.mutex.Unlock()
+	loggerInfo(p.opt.DebugMode, "closing async producer..")
+	return p.Flush(ctx)
+}
+
+// dispatch must be called with the mutex locked.
+func (p *AsyncProducer) dispatch(queueUrl string, batch *asyncBatch) {
+	delete(p.batches, queueUrl)
+	batch.timer.Stop()
+	go p.send(queueUrl, batch.entries)
+}
+
+func (p *AsyncProducer) send(queueUrl string, entries []SendMessageBatchEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opt.SendTimeout)
+	defer cancel()
+	output, err := p.client.SendMessageBatch(ctx, queueUrl, entries, &option.Producer{Default: p.opt.Default})
+	for i, entry := range entries {
+		delivery := &Delivery{QueueUrl: queueUrl, Body: entry.Body, Err: err}
+		if output != nil {
+			delivery.MessageId = output.Results[i].MessageId
+			delivery.SequenceNumber = output.Results[i].SequenceNumber
+			delivery.Err = output.Results[i].Err
+		}
+		if p.onDelivery != nil {
+			p.onDelivery(delivery)
+		}
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for range entries {
+		<-p.slots
+	}
+	p.inFlight -= len(entries)
+	if p.inFlight == 0 {
+		close(p.idle)
+	}
+}
@@ -0,0 +1,233 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/util"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"strconv"
+	"time"
+)
+
+// MaxBatchEntries is the maximum number of entries accepted by AWS SQS in each batch request.
+const MaxBatchEntries = 10
+
+// MaxBatchPayloadSize is the maximum total size in bytes of the messages, body and attributes, accepted by AWS SQS in
+// each batch request and also the maximum size of a single message.
+const MaxBatchPayloadSize = 256 * 1024
+
+// SendMessageBatchEntry represents a message to be sent by SendMessageBatch.
+type SendMessageBatchEntry struct {
+	// The content of the message, converted the same way as in SendMessage.
+	Body any
+	// Options of this message, like attributes, delay, group id and deduplication id, applied over the opts passed to
+	// SendMessageBatch.
+	Opts []*option.Producer
+}
+
+// SendMessageBatchOutput represents the result of SendMessageBatch.
+type SendMessageBatchOutput struct {
+	// Result of each entry, in the same order of the entries passed, so Results[i] is the result of the entry i.
+	Results []SendMessageBatchResult
+}
+
+// SendMessageBatchResult represents the result of one entry of SendMessageBatch.
+type SendMessageBatchResult struct {
+	// Index of the entry in the entries passed to SendMessageBatch.
+	Index int
+	// An identifier for the message, filled if the message was sent successfully.
+	MessageId string
+	// This parameter applies only to FIFO (first-in-first-out) queues. The large,
+	// non-consecutive number that Amazon SQS assigns to each message.
+	SequenceNumber string
+	// Error that occurred when preparing or sending the message, nil if it was sent successfully.
+	Err error
+}
+
+type batchEntry struct {
+	index int
+	input *sqs.SendMessageInput
+	size  int
+}
+
+// SendMessageBatch sends several messages to an SQS queue, each entry is converted the same way as in SendMessage
+// with its own options, and the entries are split automatically into batch requests with up to MaxBatchEntries
+// entries and MaxBatchPayloadSize bytes. The entries that fail by a server error, or whose request fails by an error
+// classified as retryable by the AWS SDK, are sent again up to option.Producer.BatchMaxAttempts times.
+//
+// Example usage:
+//
+//	output, err := SendMessageBatch(ctx, queueUrl, []SendMessageBatchEntry{{Body: v}}, opts...)
+//
+// # Parameters:
+//
+// - ctx (Context): The context of the request.
+// - queueUrl (string): The URL of the SQS queue.
+// - entries ([]SendMessageBatchEntry): The messages to be sent.
+// - opts (option.Producer): Optional options applied to all messages. (see option.Producer declaration for available options)
+//
+// # Returns:
+//
+// - *SendMessageBatchOutput: The result of each entry, with the message id or the error.
+// - error: ErrSendMessageBatchFailed if any entry failed, see the results for the error of each entry.
+func SendMessageBatch(ctx context.Context, queueUrl string, entries []SendMessageBatchEntry, opts ...*option.Producer) (
+	*SendMessageBatchOutput, error) {
+	return defaultClient.SendMessageBatch(ctx, queueUrl, entries, opts...)
+}
+
+// SendMessageBatch works like the package function SendMessageBatch, using the client c.
+func (c *Client) SendMessageBatch(
+	ctx context.Context,
+	queueUrl string,
+	entries []SendMessageBatchEntry,
+	opts ...*option.Producer,
+) (*SendMessageBatchOutput, error) {
+	opt := option.GetProducerByParams(opts)
+	output := &SendMessageBatchOutput{Results: make([]SendMessageBatchResult, len(entries))}
+	loggerInfo(opt.DebugMode, "getting client sqs..")
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
+	loggerInfo(opt.DebugMode, "preparing messages batch input..")
+	var pending []batchEntry
+	for i, entry := range entries {
+		output.Results[i].Index = i
+		entryOpts := append(append([]*option.Producer{}, opts...), entry.Opts...)
+		input, err := prepareMessageInput(queueUrl, entry.Body, option.GetProducerByParams(entryOpts))
+		if err != nil {
+			output.Results[i].Err = err
+			continue
+		}
+		size := getMessageInputSize(input)
+		if size > MaxBatchPayloadSize {
+			output.Results[i].Err = ErrMessageTooLarge
+			continue
+		}
+		pending = append(pending, batchEntry{index: i, input: input, size: size})
+	}
+	for attempt := 1; len(pending) != 0; attempt++ {
+		loggerInfo(opt.DebugMode, "sending messages batch size:", len(pending), "attempt:", attempt)
+		var retry []batchEntry
+		for _, chunk := range chunkBatchEntries(pending) {
+			retry = append(retry, sendBatchChunk(ctx, sqsClient, queueUrl, chunk, opt, output)...)
+		}
+		if len(retry) == 0 || attempt >= opt.BatchMaxAttempts || ctx.Err() != nil {
+			break
+		}
+		pending = retry
+		sleep(ctx, util.CalculateBackoff(attempt, 200*time.Millisecond, 2*time.Second, 2, 0.2))
+	}
+	failed := 0
+	for _, result := range output.Results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed != 0 {
+		loggerErr(opt.DebugMode, "error send messages batch, failed:", failed, "of", len(entries))
+		return output, fmt.Errorf("%w: %d of %d entries", ErrSendMessageBatchFailed, failed, len(entries))
+	}
+	loggerInfo(opt.DebugMode, "messages batch sent successfully:", output)
+	return output, nil
+}
+
+func sendBatchChunk(
+	ctx context.Context,
+	sqsClient API,
+	queueUrl string,
+	chunk []batchEntry,
+	opt *option.Producer,
+	output *SendMessageBatchOutput,
+) (retry []batchEntry) {
+	entriesById := map[string]batchEntry{}
+	requestEntries := make([]types.SendMessageBatchRequestEntry, len(chunk))
+	for i, entry := range chunk {
+		id := strconv.Itoa(entry.index)
+		entriesById[id] = entry
+		requestEntries[i] = types.SendMessageBatchRequestEntry{
+			Id:                      &id,
+			MessageBody:             entry.input.MessageBody,
+			DelaySeconds:            entry.input.DelaySeconds,
+			MessageAttributes:       entry.input.MessageAttributes,
+			MessageDeduplicationId:  entry.input.MessageDeduplicationId,
+			MessageGroupId:          entry.input.MessageGroupId,
+			MessageSystemAttributes: entry.input.MessageSystemAttributes,
+		}
+	}
+	result, err := sqsClient.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		Entries:  requestEntries,
+		QueueUrl: &queueUrl,
+	}, option.FuncByHttpClient(opt.HttpClient))
+	if err != nil {
+		loggerErr(opt.DebugMode, "error send messages batch:", err)
+		for _, entry := range chunk {
+			output.Results[entry.index].Err = err
+		}
+		// only errors classified as retryable by the AWS SDK, like throttling or timeouts, are sent again, the others,
+		// like a queue that does not exist, fail the same way on every attempt
+		if awsretry.IsErrorRetryables(awsretry.DefaultRetryables).IsErrorRetryable(err) != aws.TrueTernary {
+			return nil
+		}
+		return chunk
+	}
+	for _, successful := range result.Successful {
+		entry, ok := entriesById[aws.ToString(successful.Id)]
+		if !ok {
+			continue
+		}
+		output.Results[entry.index].MessageId = aws.ToString(successful.MessageId)
+		output.Results[entry.index].SequenceNumber = aws.ToString(successful.SequenceNumber)
+		output.Results[entry.index].Err = nil
+	}
+	for _, failed := range result.Failed {
+		entry, ok := entriesById[aws.ToString(failed.Id)]
+		if !ok {
+			continue
+		}
+		output.Results[entry.index].Err = errors.New(fmt.Sprint(aws.ToString(failed.Code), ": ",
+			aws.ToString(failed.Message)))
+		if !failed.SenderFault {
+			retry = append(retry, entry)
+		}
+	}
+	return retry
+}
+
+func chunkBatchEntries(entries []batchEntry) [][]batchEntry {
+	var result [][]batchEntry
+	var chunk []batchEntry
+	size := 0
+	for _, entry := range entries {
+		if len(chunk) == MaxBatchEntries || size+entry.size > MaxBatchPayloadSize {
+			result = append(result, chunk)
+			chunk = nil
+			size = 0
+		}
+		chunk = append(chunk, entry)
+		size += entry.size
+	}
+	if len(chunk) != 0 {
+		result = append(result, chunk)
+	}
+	return result
+}
+
+func getMessageInputSize(input *sqs.SendMessageInput) int {
+	size := len(aws.ToString(input.MessageBody))
+	for name, value := range input.MessageAttributes {
+		size += len(name) + len(aws.ToString(value.DataType)) + len(aws.ToString(value.StringValue)) +
+			len(value.BinaryValue)
+	}
+	for name, value := range input.MessageSystemAttributes {
+		size += len(name) + len(aws.ToString(value.DataType)) + len(aws.ToString(value.StringValue)) +
+			len(value.BinaryValue)
+	}
+	return size
+}
@@ -2,6 +2,11 @@ package sqs
 
 import (
 	"context"
+	"errors"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -26,3 +31,131 @@ func TestSendMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestSendMessageBatch(t *testing.T) {
+	for _, tt := range initListTestProducerBatch() {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+			_, err := SendMessageBatch(ctx, tt.queueUrl, tt.entries, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendMessageBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientSendMessageBatch(t *testing.T) {
+	api := initMockApi()
+	var entries []SendMessageBatchEntry
+	for i := 0; i < 25; i++ {
+		entries = append(entries, SendMessageBatchEntry{Body: "body test " + strconv.Itoa(i)})
+	}
+	entries[3].Body = "body test retry"
+	entries[20].Body = strings.Repeat("a", 200*1024)
+	entries[21].Body = strings.Repeat("b", 200*1024)
+	entries[22].Body = strings.Repeat("c", MaxBatchPayloadSize+1)
+	output, err := NewClientFromAPI(api).SendMessageBatch(context.TODO(), "https://sqs.mock/queue", entries)
+	if !errors.Is(err, ErrSendMessageBatchFailed) {
+		t.Errorf("SendMessageBatch() error = %v, want %v", err, ErrSendMessageBatchFailed)
+	}
+	for i, result := range output.Results {
+		if i == 22 {
+			if !errors.Is(result.Err, ErrMessageTooLarge) {
+				t.Errorf("SendMessageBatch() result %d error = %v, want %v", i, result.Err, ErrMessageTooLarge)
+			}
+		} else if result.Err != nil || result.MessageId != "mock-"+strconv.Itoa(i) {
+			t.Errorf("SendMessageBatch() result %d = %+v, want message sent", i, result)
+		}
+	}
+	for _, size := range api.batchSizes {
+		if size > MaxBatchEntries {
+			t.Errorf("SendMessageBatch() batch size = %d, want <= %d", size, MaxBatchEntries)
+		}
+	}
+	if len(api.batchSizes) != 5 {
+		t.Errorf("SendMessageBatch() batch calls = %v, want 4 chunks and 1 retry", api.batchSizes)
+	}
+}
+
+func TestClientSendMessageBatchSystemAttributes(t *testing.T) {
+	api := initMockApi()
+	opt := option.NewProducer().SetMessageSystemAttributes(option.MessageSystemAttributes{
+		AWSTraceHeader: strings.Repeat("t", 2048),
+	})
+	entries := []SendMessageBatchEntry{
+		{Body: strings.Repeat("a", 127*1024), Opts: []*option.Producer{opt}},
+		{Body: strings.Repeat("b", 127*1024), Opts: []*option.Producer{opt}},
+	}
+	_, err := NewClientFromAPI(api).SendMessageBatch(context.TODO(), "https://sqs.mock/queue", entries)
+	if err != nil {
+		t.Errorf("SendMessageBatch() error = %v", err)
+	}
+	if len(api.batchSizes) != 2 {
+		t.Errorf("SendMessageBatch() batch sizes = %v, want the entries split by the payload size", api.batchSizes)
+	}
+}
+
+func TestClientSendMessageBatchErr(t *testing.T) {
+	for _, tt := range initListTestSendMessageBatchErr() {
+		t.Run(tt.name, func(t *testing.T) {
+			api := initMockApi()
+			api.sendBatchErr = tt.err
+			output, err := NewClientFromAPI(api).SendMessageBatch(context.TODO(), "https://sqs.mock/queue",
+				[]SendMessageBatchEntry{{Body: "body test"}}, option.NewProducer().SetBatchMaxAttempts(3))
+			if !errors.Is(err, ErrSendMessageBatchFailed) || !errors.Is(output.Results[0].Err, tt.err) {
+				t.Errorf("SendMessageBatch() error = %v, result error = %v, want %v", err, output.Results[0].Err, tt.err)
+			}
+			if len(api.batchSizes) != tt.wantCalls {
+				t.Errorf("SendMessageBatch() batch calls = %d, want %d", len(api.batchSizes), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestAsyncProducer(t *testing.T) {
+	api := initMockApi()
+	var mutex sync.Mutex
+	var deliveries []*Delivery
+	producer := NewClientFromAPI(api).NewAsyncProducer(func(delivery *Delivery) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		deliveries = append(deliveries, delivery)
+	}, option.NewAsyncProducer().SetLinger(50*time.Millisecond).SetMaxBufferedMessages(15).SetDebugMode(true))
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	if err := producer.Send(ctx, "https://sqs.mock/queue", "body test"); err != nil {
+		t.Errorf("Send() error = %v, wantErr false", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	mutex.Lock()
+	if len(deliveries) != 1 || deliveries[0].Err != nil {
+		t.Errorf("Send() deliveries after linger = %d, want 1", len(deliveries))
+	}
+	mutex.Unlock()
+	for i := 0; i < 25; i++ {
+		queueUrl := "https://sqs.mock/queue-" + strconv.Itoa(i%2)
+		if err := producer.Send(ctx, queueUrl, "body test "+strconv.Itoa(i)); err != nil {
+			t.Errorf("Send() error = %v, wantErr false", err)
+		}
+	}
+	if err := producer.Close(ctx); err != nil {
+		t.Errorf("Close() error = %v, wantErr false", err)
+	}
+	if err := producer.Send(ctx, "https://sqs.mock/queue", "body test"); !errors.Is(err, ErrAsyncProducerClosed) {
+		t.Errorf("Send() error = %v, want %v", err, ErrAsyncProducerClosed)
+	}
+	if len(deliveries) != 26 {
+		t.Errorf("Close() deliveries = %d, want 26", len(deliveries))
+	}
+	for _, delivery := range deliveries {
+		if delivery.Err != nil || len(delivery.MessageId) == 0 {
+			t.Errorf("Close() delivery = %+v, want message sent", delivery)
+		}
+	}
+	for _, size := range api.batchSizes {
+		if size > MaxBatchEntries {
+			t.Errorf("Close() batch size = %d, want <= %d", size, MaxBatchEntries)
+		}
+	}
+}
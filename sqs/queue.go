@@ -2,7 +2,6 @@ package sqs
 
 import (
 	"context"
-	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/client"
 	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
@@ -320,9 +319,19 @@ type GetQueueAttributesInput struct {
 // Grant cross-account permissions to a role and a username (https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-customer-managed-policy-examples.html#grant-cross-account-permissions-to-role-and-user-name)
 // in the Amazon SQS Developer Guide.
 func CreateQueue(ctx context.Context, queueName string, opts ...*option.CreateQueue) (*sqs.CreateQueueOutput, error) {
+	return defaultClient.CreateQueue(ctx, queueName, opts...)
+}
+
+// CreateQueue works like the package function CreateQueue, using the client c.
+func (c *Client) CreateQueue(ctx context.Context, queueName string, opts ...*option.CreateQueue) (
+	*sqs.CreateQueueOutput, error) {
 	opt := option.GetCreateQueueByParams(opts)
 	loggerInfo(opt.DebugMode, "creating queue sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.CreateQueue(ctx, &sqs.CreateQueueInput{
 		QueueName:  &queueName,
 		Attributes: opt.Attributes,
@@ -353,9 +362,19 @@ func CreateQueue(ctx context.Context, queueName string, opts ...*option.CreateQu
 // a username (https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-customer-managed-policy-examples.html#grant-cross-account-permissions-to-role-and-user-name)
 // in the Amazon SQS Developer Guide.
 func TagQueue(ctx context.Context, input TagQueueInput, opts ...*option.Default) (*sqs.TagQueueOutput, error) {
+	return defaultClient.TagQueue(ctx, input, opts...)
+}
+
+// TagQueue works like the package function TagQueue, using the client c.
+func (c *Client) TagQueue(ctx context.Context, input TagQueueInput, opts ...*option.Default) (
+	*sqs.TagQueueOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "tag queue sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.TagQueue(ctx, &sqs.TagQueueInput{
 		QueueUrl: &input.QueueUrl,
 		Tags:     input.Tags,
@@ -385,10 +404,20 @@ func TagQueue(ctx context.Context, input TagQueueInput, opts ...*option.Default)
 //     to the AddPermission , RemovePermission , and SetQueueAttributes actions in
 //     your IAM policy.
 func SetQueueAttributes(ctx context.Context, input SetQueueAttributesInput, opts ...*option.Default) (
+	*sqs.SetQueueAttributesOutput, error) {
+	return defaultClient.SetQueueAttributes(ctx, input, opts...)
+}
+
+// SetQueueAttributes works like the package function SetQueueAttributes, using the client c.
+func (c *Client) SetQueueAttributes(ctx context.Context, input SetQueueAttributesInput, opts ...*option.Default) (
 	*sqs.SetQueueAttributesOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "set attributes queue sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
 		QueueUrl:   &input.QueueUrl,
 		Attributes: input.Attributes,
@@ -408,9 +437,19 @@ func SetQueueAttributes(ctx context.Context, input SetQueueAttributesInput, opts
 // a username (https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-customer-managed-policy-examples.html#grant-cross-account-permissions-to-role-and-user-name)
 // in the Amazon SQS Developer Guide.
 func UntagQueue(ctx context.Context, input UntagQueueInput, opts ...*option.Default) (*sqs.UntagQueueOutput, error) {
+	return defaultClient.UntagQueue(ctx, input, opts...)
+}
+
+// UntagQueue works like the package function UntagQueue, using the client c.
+func (c *Client) UntagQueue(ctx context.Context, input UntagQueueInput, opts ...*option.Default) (
+	*sqs.UntagQueueOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "untag queue sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.UntagQueue(ctx, &sqs.UntagQueueInput{
 		QueueUrl: &input.QueueUrl,
 		TagKeys:  input.TagKeys,
@@ -431,9 +470,19 @@ func UntagQueue(ctx context.Context, input UntagQueueInput, opts ...*option.Defa
 // but are deleted within the next minute. Messages sent to the queue after you
 // call PurgeQueue might be deleted while the queue is being purged.
 func PurgeQueue(ctx context.Context, queueUrl string, opts ...*option.Default) (*sqs.PurgeQueueOutput, error) {
+	return defaultClient.PurgeQueue(ctx, queueUrl, opts...)
+}
+
+// PurgeQueue works like the package function PurgeQueue, using the client c.
+func (c *Client) PurgeQueue(ctx context.Context, queueUrl string, opts ...*option.Default) (
+	*sqs.PurgeQueueOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "purge queue sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.PurgeQueue(ctx, &sqs.PurgeQueueInput{
 		QueueUrl: &queueUrl,
 	}, option.FuncByHttpClient(opt.HttpClient))
@@ -457,9 +506,19 @@ func PurgeQueue(ctx context.Context, queueUrl string, opts ...*option.Default) (
 // role and a username (https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-customer-managed-policy-examples.html#grant-cross-account-permissions-to-role-and-user-name)
 // in the Amazon SQS Developer Guide. The delete operation uses the HTTP GET verb.
 func DeleteQueue(ctx context.Context, queueUrl string, opts ...*option.Default) (*sqs.DeleteQueueOutput, error) {
+	return defaultClient.DeleteQueue(ctx, queueUrl, opts...)
+}
+
+// DeleteQueue works like the package function DeleteQueue, using the client c.
+func (c *Client) DeleteQueue(ctx context.Context, queueUrl string, opts ...*option.Default) (
+	*sqs.DeleteQueueOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "deleting queue sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.DeleteQueue(ctx, &sqs.DeleteQueueInput{
 		QueueUrl: &queueUrl,
 	}, option.FuncByHttpClient(opt.HttpClient))
@@ -478,9 +537,19 @@ func DeleteQueue(ctx context.Context, queueUrl string, opts ...*option.Default)
 // AddPermission or see Allow Developers to Write Messages to a Shared Queue (https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-writing-an-sqs-policy.html#write-messages-to-shared-queue)
 // in the Amazon SQS Developer Guide.
 func GetQueueUrl(ctx context.Context, input GetQueueUrlInput, opts ...*option.Default) (*sqs.GetQueueUrlOutput, error) {
+	return defaultClient.GetQueueUrl(ctx, input, opts...)
+}
+
+// GetQueueUrl works like the package function GetQueueUrl, using the client c.
+func (c *Client) GetQueueUrl(ctx context.Context, input GetQueueUrlInput, opts ...*option.Default) (
+	*sqs.GetQueueUrlOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "get queue url sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{
 		QueueName:              &input.QueueName,
 		QueueOwnerAWSAccountId: input.QueueOwnerAWSAccountId,
@@ -497,10 +566,20 @@ func GetQueueUrl(ctx context.Context, input GetQueueUrlInput, opts ...*option.De
 // To determine whether a queue is FIFO (https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/FIFO-queues.html)
 // you can check whether QueueName ends with the .fifo suffix.
 func GetQueueAttributes(ctx context.Context, input GetQueueAttributesInput, opts ...*option.Default) (
+	*sqs.GetQueueAttributesOutput, error) {
+	return defaultClient.GetQueueAttributes(ctx, input, opts...)
+}
+
+// GetQueueAttributes works like the package function GetQueueAttributes, using the client c.
+func (c *Client) GetQueueAttributes(ctx context.Context, input GetQueueAttributesInput, opts ...*option.Default) (
 	*sqs.GetQueueAttributesOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "get queue attributes sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
 		QueueUrl:       &input.QueueUrl,
 		AttributeNames: input.AttributeNames,
@@ -526,9 +605,18 @@ func GetQueueAttributes(ctx context.Context, input GetQueueAttributesInput, opts
 // information, see Grant cross-account permissions to a role and a username (https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-customer-managed-policy-examples.html#grant-cross-account-permissions-to-role-and-user-name)
 // in the Amazon SQS Developer Guide.
 func ListQueues(ctx context.Context, opts ...*option.ListQueues) (*sqs.ListQueuesOutput, error) {
+	return defaultClient.ListQueues(ctx, opts...)
+}
+
+// ListQueues works like the package function ListQueues, using the client c.
+func (c *Client) ListQueues(ctx context.Context, opts ...*option.ListQueues) (*sqs.ListQueuesOutput, error) {
 	opt := option.GetListQueuesByParams(opts)
 	loggerInfo(opt.DebugMode, "list queue tags sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.ListQueues(ctx, &sqs.ListQueuesInput{
 		MaxResults:      &opt.MaxResults,
 		NextToken:       opt.NextToken,
@@ -549,9 +637,19 @@ func ListQueues(ctx context.Context, opts ...*option.ListQueues) (*sqs.ListQueue
 // a username (https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-customer-managed-policy-examples.html#grant-cross-account-permissions-to-role-and-user-name)
 // in the Amazon SQS Developer Guide.
 func ListQueueTags(ctx context.Context, queueUrl string, opts ...*option.Default) (*sqs.ListQueueTagsOutput, error) {
+	return defaultClient.ListQueueTags(ctx, queueUrl, opts...)
+}
+
+// ListQueueTags works like the package function ListQueueTags, using the client c.
+func (c *Client) ListQueueTags(ctx context.Context, queueUrl string, opts ...*option.Default) (
+	*sqs.ListQueueTagsOutput, error) {
 	opt := option.GetDefaultByParams(opts)
 	loggerInfo(opt.DebugMode, "list queue tags sqs..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.ListQueueTags(ctx, &sqs.ListQueueTagsInput{
 		QueueUrl: &queueUrl,
 	}, option.FuncByHttpClient(opt.HttpClient))
@@ -576,9 +674,19 @@ func ListQueueTags(ctx context.Context, queueUrl string, opts ...*option.Default
 // in the Amazon SQS Developer Guide.
 func ListDeadLetterSourceQueues(ctx context.Context, queueUrl string, opts ...*option.ListDeadLetterSourceQueues) (
 	*sqs.ListDeadLetterSourceQueuesOutput, error) {
+	return defaultClient.ListDeadLetterSourceQueues(ctx, queueUrl, opts...)
+}
+
+// ListDeadLetterSourceQueues works like the package function ListDeadLetterSourceQueues, using the client c.
+func (c *Client) ListDeadLetterSourceQueues(ctx context.Context, queueUrl string,
+	opts ...*option.ListDeadLetterSourceQueues) (*sqs.ListDeadLetterSourceQueuesOutput, error) {
 	opt := option.GetListDeadLetterSourceQueuesByParams(opts)
 	loggerInfo(opt.DebugMode, "listing dead letter source queues..")
-	sqsClient := client.GetClient(ctx)
+	sqsClient, err := c.getApi(ctx)
+	if err != nil {
+		loggerErr(opt.DebugMode, "error get client sqs:", err)
+		return nil, err
+	}
 	output, err := sqsClient.ListDeadLetterSourceQueues(ctx, &sqs.ListDeadLetterSourceQueuesInput{
 		QueueUrl:   &queueUrl,
 		MaxResults: &opt.MaxResults,
@@ -0,0 +1,169 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"reflect"
+)
+
+// Queue is a handle of an SQS queue bound to the types of the body and of the message attributes of its messages,
+// created once and shared, so the url and the options are not repeated on each call and the payloads sent and
+// consumed are checked at compile time.
+//
+// Example usage:
+//
+//	orders := sqs.NewQueue[Order, OrderAttributes](queueUrl, option.NewQueue().SetCodec(codec.JSON))
+//	_, err := orders.SendWithAttributes(ctx, Order{Id: "1"}, OrderAttributes{})
+//	err = orders.Consume(ctx, handler)
+//
+// The options of the Queue are applied before the options passed to each method.
+type Queue[Body, MessageAttributes any] struct {
+	url    string
+	client *Client
+	opt    *option.Queue
+}
+
+// QueueBatchEntry represents a message to be sent by Queue.SendBatch.
+type QueueBatchEntry[Body, MessageAttributes any] struct {
+	// The content of the message, converted the same way as in SendMessage.
+	Body Body
+	// Message attributes of this message, applied over the Opts, if nil the message attributes of the Opts are used.
+	MessageAttributes *MessageAttributes
+	// Options of this message, applied over the opts passed to Queue.SendBatch.
+	Opts []*option.Producer
+}
+
+// NewQueue creates a Queue for the queueUrl using the default client of the package, see NewQueueWithClient.
+func NewQueue[Body, MessageAttributes any](queueUrl string, opts ...*option.Queue) *Queue[Body, MessageAttributes] {
+	return NewQueueWithClient[Body, MessageAttributes](nil, queueUrl, opts...)
+}
+
+// NewQueueWithClient creates a Queue for the queueUrl using the client c, if c is nil the default client of the
+// package is used.
+//
+// # Parameters
+//
+// - c: client used by all operations of the queue
+// - queueUrl: url of the queue
+// - opts: options of the queue, like the codec and the default options of the producer and consumer
+func NewQueueWithClient[Body, MessageAttributes any](
+	c *Client,
+	queueUrl string,
+	opts ...*option.Queue,
+) *Queue[Body, MessageAttributes] {
+	return &Queue[Body, MessageAttributes]{
+		url:    queueUrl,
+		client: getClient(c),
+		opt:    option.GetQueueByParams(opts),
+	}
+}
+
+// Url returns the url of the queue.
+func (q *Queue[Body, MessageAttributes]) Url() string {
+	return q.url
+}
+
+// Send sends the body to the queue, same as SendMessage. The option.Producer.MessageAttributes of the opts must be a
+// MessageAttributes or a *MessageAttributes, otherwise ErrMessageAttributesType is returned, use SendWithAttributes
+// to have them checked at compile time.
+func (q *Queue[Body, MessageAttributes]) Send(ctx context.Context, body Body, opts ...*option.Producer) (
+	*sqs.SendMessageOutput, error) {
+	opts = q.producerOpts(opts)
+	if err := q.checkMessageAttributes(opts); err != nil {
+		return nil, err
+	}
+	return q.client.SendMessage(ctx, q.url, body, opts...)
+}
+
+// SendWithAttributes sends the body with the messageAttributes to the queue, same as Send, the messageAttributes are
+// applied over the opts.
+func (q *Queue[Body, MessageAttributes]) SendWithAttributes(
+	ctx context.Context,
+	body Body,
+	messageAttributes MessageAttributes,
+	opts ...*option.Producer,
+) (*sqs.SendMessageOutput, error) {
+	return q.Send(ctx, body, append(opts[:len(opts):len(opts)],
+		option.NewProducer().SetMessageAttributes(messageAttributes))...)
+}
+
+// SendBatch sends several messages to the queue, same as SendMessageBatch. The message attributes of each message
+// follow the same rule of Send, if one of them is not a MessageAttributes no message is sent.
+func (q *Queue[Body, MessageAttributes]) SendBatch(
+	ctx context.Context,
+	entries []QueueBatchEntry[Body, MessageAttributes],
+	opts ...*option.Producer,
+) (*SendMessageBatchOutput, error) {
+	opts = q.producerOpts(opts)
+	batchEntries := make([]SendMessageBatchEntry, len(entries))
+	for i, entry := range entries {
+		entryOpts := entry.Opts
+		if entry.MessageAttributes != nil {
+			entryOpts = append(entryOpts[:len(entryOpts):len(entryOpts)],
+				option.NewProducer().SetMessageAttributes(*entry.MessageAttributes))
+		}
+		if err := q.checkMessageAttributes(append(opts[:len(opts):len(opts)], entryOpts...)); err != nil {
+			return nil, fmt.Errorf("sqs: entry %d: %w", i, err)
+		}
+		batchEntries[i] = SendMessageBatchEntry{Body: entry.Body, Opts: entryOpts}
+	}
+	return q.client.SendMessageBatch(ctx, q.url, batchEntries, opts...)
+}
+
+// Consume processes the messages of the queue with the handler until ctx is canceled, same as ReceiveMessage.
+func (q *Queue[Body, MessageAttributes]) Consume(
+	ctx context.Context,
+	handler HandlerConsumerFunc[Body, MessageAttributes],
+	opts ...*option.Consumer,
+) error {
+	return ReceiveMessageWithClient[Body, MessageAttributes](ctx, q.client, q.url, handler, q.consumerOpts(opts)...)
+}
+
+// Receive searches the queue for messages once, same as Receive.
+func (q *Queue[Body, MessageAttributes]) Receive(ctx context.Context, opts ...*option.Consumer) (
+	*ReceiveOutput[Body, MessageAttributes], error) {
+	return ReceiveWithClient[Body, MessageAttributes](ctx, q.client, q.url, q.consumerOpts(opts)...)
+}
+
+// Purge deletes the available messages of the queue, same as PurgeQueue.
+func (q *Queue[Body, MessageAttributes]) Purge(ctx context.Context, opts ...*option.Default) (
+	*sqs.PurgeQueueOutput, error) {
+	return q.client.PurgeQueue(ctx, q.url, opts...)
+}
+
+// Attributes gets the attributes of the queue, same as GetQueueAttributes, if no attributeNames are informed all
+// attributes are returned.
+func (q *Queue[Body, MessageAttributes]) Attributes(
+	ctx context.Context,
+	attributeNames []types.QueueAttributeName,
+	opts ...*option.Default,
+) (*sqs.GetQueueAttributesOutput, error) {
+	if len(attributeNames) == 0 {
+		attributeNames = []types.QueueAttributeName{types.QueueAttributeNameAll}
+	}
+	return q.client.GetQueueAttributes(ctx, GetQueueAttributesInput{
+		QueueUrl:       q.url,
+		AttributeNames: attributeNames,
+	}, opts...)
+}
+
+func (q *Queue[Body, MessageAttributes]) checkMessageAttributes(opts []*option.Producer) error {
+	switch messageAttributes := option.GetProducerByParams(opts).MessageAttributes.(type) {
+	case nil, MessageAttributes, *MessageAttributes:
+		return nil
+	default:
+		return fmt.Errorf("%w: got %T, want %s", ErrMessageAttributesType, messageAttributes,
+			reflect.TypeOf((*MessageAttributes)(nil)).Elem())
+	}
+}
+
+func (q *Queue[Body, MessageAttributes]) producerOpts(opts []*option.Producer) []*option.Producer {
+	return append([]*option.Producer{{Codec: q.opt.Codec}, q.opt.Producer}, opts...)
+}
+
+func (q *Queue[Body, MessageAttributes]) consumerOpts(opts []*option.Consumer) []*option.Consumer {
+	return append([]*option.Consumer{{Codec: q.opt.Codec}, q.opt.Consumer}, opts...)
+}
@@ -2,6 +2,10 @@ package sqs
 
 import (
 	"context"
+	"errors"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/codec"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/option"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/sqs/sqstest"
 	"os"
 	"testing"
 	"time"
@@ -163,3 +167,79 @@ func TestListDeadLetterSourceQueues(t *testing.T) {
 		})
 	}
 }
+
+func TestQueueHandle(t *testing.T) {
+	ctx := context.TODO()
+	fake := sqstest.NewFake()
+	c := NewClientFromAPI(fake)
+	queueUrl := fake.NewQueue("handle", nil)
+	queue := NewQueueWithClient[test, messageAttTest](c, queueUrl, option.NewQueue().
+		SetCodec(codec.JSON).
+		SetProducer(option.NewProducer().SetMessageAttributes(initMessageAttTest())).
+		SetConsumer(option.NewConsumer().SetDeleteMessageProcessedSuccess(true).SetDelayQueryLoop(200*time.Millisecond)))
+	if queue.Url() != queueUrl {
+		t.Errorf("Url() = %s, want %s", queue.Url(), queueUrl)
+	}
+	_, err := queue.SendWithAttributes(ctx, initTestStruct(), initMessageAttTest())
+	if err != nil {
+		t.Fatalf("SendWithAttributes() error = %v", err)
+	}
+	messageAttributes := initMessageAttTest()
+	_, err = queue.SendBatch(ctx, []QueueBatchEntry[test, messageAttTest]{
+		{Body: initTestStruct()},
+		{Body: initTestStruct(), MessageAttributes: &messageAttributes},
+	})
+	if err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+	wrongType := option.NewProducer().SetMessageAttributes(initTestStruct())
+	if _, err = queue.Send(ctx, initTestStruct(), wrongType); !errors.Is(err, ErrMessageAttributesType) {
+		t.Errorf("Send() error = %v, want %v", err, ErrMessageAttributesType)
+	}
+	_, err = queue.SendBatch(ctx, []QueueBatchEntry[test, messageAttTest]{
+		{Body: initTestStruct()},
+		{Body: initTestStruct(), Opts: []*option.Producer{wrongType}},
+	})
+	if !errors.Is(err, ErrMessageAttributesType) {
+		t.Errorf("SendBatch() error = %v, want %v", err, ErrMessageAttributesType)
+	}
+	_, err = c.SendMessage(ctx, queueUrl, `{"name":1}`)
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	output, err := queue.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	} else if len(output.Messages) != 3 || len(output.Failed) != 1 {
+		t.Fatalf("Receive() messages = %d, failed = %d, want 3 and 1", len(output.Messages), len(output.Failed))
+	}
+	for _, message := range output.Messages {
+		if message.Body.Name != initTestStruct().Name || message.MessageAttributes.Name != initMessageAttTest().Name {
+			t.Errorf("Receive() message = %+v, want converted body and message attributes", message)
+		}
+	}
+	if !errors.Is(output.Failed[0], ErrParseBody) {
+		t.Errorf("Receive() failed = %v, want %v", output.Failed[0], ErrParseBody)
+	}
+	attributes, err := queue.Attributes(ctx, nil)
+	if err != nil || attributes.Attributes["ApproximateNumberOfMessagesNotVisible"] != "4" {
+		t.Errorf("Attributes() attributes = %v, err = %v, want 4 messages not visible", attributes, err)
+	}
+	_, err = queue.Purge(ctx)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	_, err = queue.Send(ctx, initTestStruct())
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	ctxConsumer, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	err = queue.Consume(ctxConsumer, initHandleConsumer[test, messageAttTest])
+	if err != nil {
+		t.Errorf("Consume() error = %v", err)
+	}
+	if messages := fake.Messages(queueUrl); len(messages) != 0 {
+		t.Errorf("Consume() messages = %v, want deleted", messages)
+	}
+}
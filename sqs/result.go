@@ -0,0 +1,70 @@
+package sqs
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxMessageAttributes is the maximum number of message attributes accepted by AWS SQS in each message.
+const MaxMessageAttributes = 10
+
+// FailureReasonAttributeName is the name of the message attribute filled with the failure reason when a message is
+// forwarded to another queue by the consumer, like in DeadLetter. It's only added if the message has less than
+// MaxMessageAttributes message attributes, otherwise the reason is reported to option.Consumer.OnError with
+// ErrForwardAttributesDropped.
+const FailureReasonAttributeName = "FailureReason"
+
+// SourceQueueUrlAttributeName is the name of the message attribute filled with the url of the original queue when a
+// message is forwarded to another queue by the consumer, like in DeadLetter. It's only added if there is still room
+// for it after FailureReasonAttributeName, so the forwarded message does not exceed MaxMessageAttributes, otherwise
+// it's reported to option.Consumer.OnError with ErrForwardAttributesDropped.
+const SourceQueueUrlAttributeName = "SourceQueueUrl"
+
+type handlerAction int
+
+const (
+	handlerActionRetryAfter handlerAction = iota + 1
+	handlerActionDrop
+	handlerActionDeadLetter
+)
+
+// HandlerResult is a typed outcome of the consumer handler, returned as error by HandlerConsumerFunc to tell the
+// consumer what to do with the message, use RetryAfter, Drop or DeadLetter to create it. Any other error keeps the
+// default behavior, the message is not removed and appears again in the queue after the visibility timeout.
+type HandlerResult struct {
+	action handlerAction
+	delay  time.Duration
+	reason string
+}
+
+// Drop is returned by the handler to remove the message from the queue without processing it successfully.
+var Drop error = &HandlerResult{action: handlerActionDrop}
+
+// RetryAfter is returned by the handler to change the visibility timeout of the message to d, so it appears again in
+// the queue after this delay, useful to apply backoff on failures.
+func RetryAfter(d time.Duration) error {
+	return &HandlerResult{action: handlerActionRetryAfter, delay: d}
+}
+
+// DeadLetter is returned by the handler to send the message to the queue filled in
+// option.Consumer.DeadLetterQueueUrl and then remove it from the original queue. The forwarded message keeps the
+// body and message attributes, adding the reason in FailureReasonAttributeName and the original queue url in
+// SourceQueueUrlAttributeName while the message attributes fit in MaxMessageAttributes, the original message
+// attributes are never dropped. The reason and the url that do not fit are reported to option.Consumer.OnError with
+// ErrForwardAttributesDropped.
+func DeadLetter(reason string) error {
+	return &HandlerResult{action: handlerActionDeadLetter, reason: reason}
+}
+
+func (h *HandlerResult) Error() string {
+	switch h.action {
+	case handlerActionRetryAfter:
+		return fmt.Sprint("sqs: retry message after ", h.delay.String())
+	case handlerActionDrop:
+		return "sqs: drop message"
+	case handlerActionDeadLetter:
+		return fmt.Sprint("sqs: dead letter message: ", h.reason)
+	default:
+		return "sqs: unknown handler result"
+	}
+}
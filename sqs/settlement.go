@@ -0,0 +1,146 @@
+package sqs
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"sync"
+	"time"
+)
+
+type messageSettlement struct {
+	mutex   sync.Mutex
+	settled bool
+	ack     func() error
+	nack    func(delay time.Duration) error
+	extend  func(d time.Duration) error
+}
+
+// Ack acknowledges the message, removing it from the queue without waiting for the handler to return, useful to
+// confirm the message as soon as the essential work is done. After Ack, the return of the handler is ignored.
+//
+// # Returns
+//
+// - error: ErrMessageAlreadySettled if the message was already settled by Ack or Nack, ErrMessageNotSettleable if
+// the Context was not created by a consumer, or ErrConsumerStopped if the consumer already stopped, like when the
+// handler keeps running after the option.Consumer.DrainTimeout, in this case the message is not removed and appears
+// again in the queue after the visibility timeout.
+func (c *Context[Body, MessageAttributes]) Ack() error {
+	return c.settlement.settle(func() error {
+		return c.settlement.ack()
+	})
+}
+
+// Nack gives up processing the message, changing its visibility timeout to delay, so it appears again in the queue
+// after this delay. After Nack, the return of the handler is ignored.
+//
+// # Returns
+//
+// - error: ErrMessageAlreadySettled if the message was already settled by Ack or Nack, ErrMessageNotSettleable if
+// the Context was not created by a consumer, or the error returned from AWS SQS.
+func (c *Context[Body, MessageAttributes]) Nack(delay time.Duration) error {
+	return c.settlement.settle(func() error {
+		return c.settlement.nack(delay)
+	})
+}
+
+// ExtendVisibility changes the visibility timeout of the message to d, counted from now, giving the handler more
+// time to process it before it appears again in the queue.
+//
+// # Returns
+//
+// - error: ErrMessageAlreadySettled if the message was already settled by Ack or Nack, ErrMessageNotSettleable if
+// the Context was not created by a consumer, or the error returned from AWS SQS.
+func (c *Context[Body, MessageAttributes]) ExtendVisibility(d time.Duration) error {
+	return c.settlement.extendVisibility(d)
+}
+
+// Ack acknowledges the message, same as Context.Ack.
+func (c *SimpleContext[Body]) Ack() error {
+	return c.settlement.settle(func() error {
+		return c.settlement.ack()
+	})
+}
+
+// Nack gives up processing the message, same as Context.Nack.
+func (c *SimpleContext[Body]) Nack(delay time.Duration) error {
+	return c.settlement.settle(func() error {
+		return c.settlement.nack(delay)
+	})
+}
+
+// ExtendVisibility changes the visibility timeout of the message, same as Context.ExtendVisibility.
+func (c *SimpleContext[Body]) ExtendVisibility(d time.Duration) error {
+	return c.settlement.extendVisibility(d)
+}
+
+func newMessageSettlement(
+	job *consumerJob,
+	queueUrl string,
+	message types.Message,
+	stopHeartbeat func(),
+) *messageSettlement {
+	changeVisibility := func(d time.Duration) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := job.client.ChangeMessageVisibility(ctx, ChangeMessageVisibilityInput{
+			QueueUrl:          queueUrl,
+			ReceiptHandle:     *message.ReceiptHandle,
+			VisibilityTimeout: d,
+		}, &job.opt.Default)
+		return err
+	}
+	return &messageSettlement{
+		ack: func() error {
+			stopHeartbeat()
+			return job.deleteMessage(*message.ReceiptHandle)
+		},
+		nack: func(delay time.Duration) error {
+			stopHeartbeat()
+			return changeVisibility(delay)
+		},
+		extend: changeVisibility,
+	}
+}
+
+func (s *messageSettlement) settle(f func() error) error {
+	if s == nil {
+		return ErrMessageNotSettleable
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.settled {
+		return ErrMessageAlreadySettled
+	}
+	err := f()
+	if err == nil {
+		s.settled = true
+	}
+	return err
+}
+
+func (s *messageSettlement) extendVisibility(d time.Duration) error {
+	if s == nil {
+		return ErrMessageNotSettleable
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.settled {
+		return ErrMessageAlreadySettled
+	}
+	return s.extend(d)
+}
+
+// markSettled marks the message as settled by the return of the handler, it returns false if the handler already
+// settled the message with Ack or Nack.
+func (s *messageSettlement) markSettled() bool {
+	if s == nil {
+		return true
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.settled {
+		return false
+	}
+	s.settled = true
+	return true
+}
@@ -0,0 +1,833 @@
+package sqstest
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/GabrielHCataldo/go-aws-sqs-template/internal/util"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxBatchEntries = 10
+const maxBatchSize = 262144
+const maxMessageAttributes = 10
+const maxVisibilityTimeout = 43200
+const maxDelaySeconds = 900
+const maxWaitTimeSeconds = 20
+const deduplicationInterval = 5 * time.Minute
+const pollInterval = 100 * time.Millisecond
+
+const moveTaskStatusRunning = "RUNNING"
+const moveTaskStatusCompleted = "COMPLETED"
+
+var batchEntryIdRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,80}$`)
+var messageAttributeNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,256}$`)
+
+type message struct {
+	id                  string
+	body                string
+	attributes          map[string]types.MessageAttributeValue
+	traceHeader         *string
+	md5OfBody           string
+	md5OfAttributes     string
+	groupId             string
+	deduplicationId     string
+	sequenceNumber      string
+	deadLetterSourceArn string
+	sentAt              time.Time
+	firstReceivedAt     time.Time
+	visibleAt           time.Time
+	receiveCount        int
+	receiptHandle       string
+}
+
+type deduplicationEntry struct {
+	message   *message
+	expiresAt time.Time
+}
+
+type moveTask struct {
+	handle         string
+	sourceArn      string
+	destinationArn *string
+	maxPerSecond   *int32
+	startedAt      time.Time
+	status         string
+	moved          int64
+	toMove         int64
+}
+
+// SendMessage stores the message in the queue, visible after the delay of the message or of the queue. In FIFO
+// queues, the MessageGroupId is required and the messages with the same deduplication id sent in a 5 minutes interval
+// are accepted but stored only once.
+func (f *Fake) SendMessage(_ context.Context, params *sqs.SendMessageInput, _ ...func(*sqs.Options)) (
+	*sqs.SendMessageOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	m, err := f.sendMessage(q, types.SendMessageBatchRequestEntry{
+		MessageBody:             params.MessageBody,
+		DelaySeconds:            params.DelaySeconds,
+		MessageAttributes:       params.MessageAttributes,
+		MessageDeduplicationId:  params.MessageDeduplicationId,
+		MessageGroupId:          params.MessageGroupId,
+		MessageSystemAttributes: params.MessageSystemAttributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.SendMessageOutput{
+		MessageId:              aws.String(m.id),
+		MD5OfMessageBody:       aws.String(m.md5OfBody),
+		MD5OfMessageAttributes: toStringPointer(m.md5OfAttributes),
+		SequenceNumber:         toStringPointer(m.sequenceNumber),
+	}, nil
+}
+
+// SendMessageBatch works like SendMessage for each entry, the entries that fail are returned in Failed.
+func (f *Fake) SendMessageBatch(_ context.Context, params *sqs.SendMessageBatchInput, _ ...func(*sqs.Options)) (
+	*sqs.SendMessageBatchOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]*string, len(params.Entries))
+	size := 0
+	for i, entry := range params.Entries {
+		ids[i] = entry.Id
+		size += getMessageSize(aws.ToString(entry.MessageBody), entry.MessageAttributes)
+	}
+	err = validateBatchEntryIds(ids)
+	if err != nil {
+		return nil, err
+	} else if size > maxBatchSize {
+		return nil, &types.BatchRequestTooLong{Message: aws.String(fmt.Sprint("Batch requests cannot be longer ",
+			"than ", maxBatchSize, " bytes."))}
+	}
+	output := &sqs.SendMessageBatchOutput{}
+	for _, entry := range params.Entries {
+		m, err := f.sendMessage(q, entry)
+		if err != nil {
+			output.Failed = append(output.Failed, newBatchResultErrorEntry(entry.Id, err))
+			continue
+		}
+		output.Successful = append(output.Successful, types.SendMessageBatchResultEntry{
+			Id:                     entry.Id,
+			MessageId:              aws.String(m.id),
+			MD5OfMessageBody:       aws.String(m.md5OfBody),
+			MD5OfMessageAttributes: toStringPointer(m.md5OfAttributes),
+			SequenceNumber:         toStringPointer(m.sequenceNumber),
+		})
+	}
+	return output, nil
+}
+
+// ReceiveMessage returns up to MaxNumberOfMessages visible messages, hiding them for the VisibilityTimeout of the
+// request or of the queue and incrementing their receive count. If there are no visible messages, it waits for
+// WaitTimeSeconds, or the ReceiveMessageWaitTimeSeconds of the queue, until some message is available or the ctx is
+// done.
+//
+// In FIFO queues, the messages of a group are returned in order and no message of a group is returned while
+// another message of the same group is in flight. If the queue has a RedrivePolicy, the messages received more than
+// maxReceiveCount times are moved to the dead-letter queue instead of being returned.
+func (f *Fake) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (
+	*sqs.ReceiveMessageOutput, error) {
+	f.mutex.Lock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		f.mutex.Unlock()
+		return nil, err
+	}
+	wait := q.getDuration(types.QueueAttributeNameReceiveMessageWaitTimeSeconds)
+	f.mutex.Unlock()
+	maxNumberOfMessages := int(params.MaxNumberOfMessages)
+	if maxNumberOfMessages == 0 {
+		maxNumberOfMessages = 1
+	}
+	if maxNumberOfMessages < 1 || maxNumberOfMessages > maxBatchEntries {
+		return nil, invalidParameter("Value %d for parameter MaxNumberOfMessages is invalid. Reason: Must be "+
+			"between 1 and %d, if provided.", params.MaxNumberOfMessages, maxBatchEntries)
+	} else if params.VisibilityTimeout < 0 || params.VisibilityTimeout > maxVisibilityTimeout {
+		return nil, invalidParameter("Value %d for parameter VisibilityTimeout is invalid. Reason: Must be "+
+			"between 0 and %d, if provided.", params.VisibilityTimeout, maxVisibilityTimeout)
+	} else if params.WaitTimeSeconds < 0 || params.WaitTimeSeconds > maxWaitTimeSeconds {
+		return nil, invalidParameter("Value %d for parameter WaitTimeSeconds is invalid. Reason: Must be "+
+			"between 0 and %d, if provided.", params.WaitTimeSeconds, maxWaitTimeSeconds)
+	}
+	if params.WaitTimeSeconds != 0 {
+		wait = time.Duration(params.WaitTimeSeconds) * time.Second
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		f.mutex.Lock()
+		q, err = f.getQueue(params.QueueUrl)
+		if err != nil {
+			f.mutex.Unlock()
+			return nil, err
+		}
+		messages := f.receiveMessages(q, params, maxNumberOfMessages)
+		notify := f.notify
+		f.mutex.Unlock()
+		if len(messages) != 0 || !time.Now().Before(deadline) {
+			return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+		}
+		timer := time.NewTimer(min(time.Until(deadline), pollInterval))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-notify:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// DeleteMessage deletes the message of the receipt handle, if the handle is not the one of the last receive of the
+// message, the request succeeds but the message is not deleted, like in AWS SQS.
+func (f *Fake) DeleteMessage(_ context.Context, params *sqs.DeleteMessageInput, _ ...func(*sqs.Options)) (
+	*sqs.DeleteMessageOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	err = q.deleteMessage(aws.ToString(params.ReceiptHandle))
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// DeleteMessageBatch works like DeleteMessage for each entry, the entries that fail are returned in Failed.
+func (f *Fake) DeleteMessageBatch(_ context.Context, params *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (
+	*sqs.DeleteMessageBatchOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]*string, len(params.Entries))
+	for i, entry := range params.Entries {
+		ids[i] = entry.Id
+	}
+	err = validateBatchEntryIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range params.Entries {
+		err = q.deleteMessage(aws.ToString(entry.ReceiptHandle))
+		if err != nil {
+			output.Failed = append(output.Failed, newBatchResultErrorEntry(entry.Id, err))
+			continue
+		}
+		output.Successful = append(output.Successful, types.DeleteMessageBatchResultEntry{Id: entry.Id})
+	}
+	return output, nil
+}
+
+// ChangeMessageVisibility changes the visibility timeout of a message in flight, counting from now, zero makes the
+// message visible immediately.
+func (f *Fake) ChangeMessageVisibility(_ context.Context, params *sqs.ChangeMessageVisibilityInput,
+	_ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	err = f.changeMessageVisibility(q, aws.ToString(params.ReceiptHandle), params.VisibilityTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+// ChangeMessageVisibilityBatch works like ChangeMessageVisibility for each entry, the entries that fail are returned
+// in Failed.
+func (f *Fake) ChangeMessageVisibilityBatch(_ context.Context, params *sqs.ChangeMessageVisibilityBatchInput,
+	_ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]*string, len(params.Entries))
+	for i, entry := range params.Entries {
+		ids[i] = entry.Id
+	}
+	err = validateBatchEntryIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	output := &sqs.ChangeMessageVisibilityBatchOutput{}
+	for _, entry := range params.Entries {
+		err = f.changeMessageVisibility(q, aws.ToString(entry.ReceiptHandle), entry.VisibilityTimeout)
+		if err != nil {
+			output.Failed = append(output.Failed, newBatchResultErrorEntry(entry.Id, err))
+			continue
+		}
+		output.Successful = append(output.Successful, types.ChangeMessageVisibilityBatchResultEntry{Id: entry.Id})
+	}
+	return output, nil
+}
+
+// StartMessageMoveTask moves the messages that are not in flight from the dead-letter queue of the SourceArn to the
+// queue of the DestinationArn or, if it is not informed, back to the queue where each message came from. The messages
+// are moved immediately, so the task is created already completed.
+func (f *Fake) StartMessageMoveTask(_ context.Context, params *sqs.StartMessageMoveTaskInput,
+	_ ...func(*sqs.Options)) (*sqs.StartMessageMoveTaskOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if params.SourceArn == nil || *params.SourceArn == "" {
+		return nil, missingParameter("SourceArn")
+	}
+	source, ok := f.getQueueByArn(*params.SourceArn)
+	if !ok {
+		return nil, resourceNotFound("SourceArn")
+	} else if source.isFifo() {
+		return nil, &types.UnsupportedOperation{Message: aws.String("FIFO queues are not supported as source of " +
+			"message move tasks.")}
+	} else if len(f.getDeadLetterSourceQueueUrls(source)) == 0 {
+		return nil, invalidParameter("Source queue must be configured as a Dead Letter Queue.")
+	}
+	var destination *queue
+	if params.DestinationArn != nil {
+		destination, ok = f.getQueueByArn(*params.DestinationArn)
+		if !ok {
+			return nil, resourceNotFound("DestinationArn")
+		}
+	}
+	if params.MaxNumberOfMessagesPerSecond != nil && (*params.MaxNumberOfMessagesPerSecond < 1 ||
+		*params.MaxNumberOfMessagesPerSecond > 500) {
+		return nil, invalidParameter("Value %d for parameter MaxNumberOfMessagesPerSecond is invalid. Reason: Must "+
+			"be between 1 and 500.", *params.MaxNumberOfMessagesPerSecond)
+	}
+	now := f.now()
+	source.expireMessages(now)
+	task := &moveTask{
+		handle:         base64.StdEncoding.EncodeToString([]byte(source.arn + ":" + util.NewUUID())),
+		sourceArn:      source.arn,
+		destinationArn: params.DestinationArn,
+		maxPerSecond:   params.MaxNumberOfMessagesPerSecond,
+		startedAt:      now,
+		status:         moveTaskStatusRunning,
+		toMove:         int64(len(source.messages)),
+	}
+	var remaining []*message
+	for _, m := range source.messages {
+		target := destination
+		if target == nil {
+			target, _ = f.getQueueByArn(m.deadLetterSourceArn)
+		}
+		if target == nil || m.isInFlight(now) {
+			remaining = append(remaining, m)
+			continue
+		}
+		f.moveMessage(m, target, "", now)
+		task.moved++
+	}
+	source.messages = remaining
+	task.status = moveTaskStatusCompleted
+	f.tasks = append(f.tasks, task)
+	return &sqs.StartMessageMoveTaskOutput{TaskHandle: aws.String(task.handle)}, nil
+}
+
+// CancelMessageMoveTask cancels a running message move task, as the tasks of the Fake complete immediately, it
+// returns an error for any existing task.
+func (f *Fake) CancelMessageMoveTask(_ context.Context, params *sqs.CancelMessageMoveTaskInput,
+	_ ...func(*sqs.Options)) (*sqs.CancelMessageMoveTaskOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if params.TaskHandle == nil || *params.TaskHandle == "" {
+		return nil, missingParameter("TaskHandle")
+	}
+	for _, task := range f.tasks {
+		if task.handle != *params.TaskHandle {
+			continue
+		} else if task.status != moveTaskStatusRunning {
+			return nil, &types.UnsupportedOperation{Message: aws.String("Only active tasks can be cancelled.")}
+		}
+		return &sqs.CancelMessageMoveTaskOutput{ApproximateNumberOfMessagesMoved: task.moved}, nil
+	}
+	return nil, resourceNotFound("TaskHandle")
+}
+
+// ListMessageMoveTasks returns the most recent message move tasks of the source queue, up to MaxResults, default 1.
+func (f *Fake) ListMessageMoveTasks(_ context.Context, params *sqs.ListMessageMoveTasksInput,
+	_ ...func(*sqs.Options)) (*sqs.ListMessageMoveTasksOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if params.SourceArn == nil || *params.SourceArn == "" {
+		return nil, missingParameter("SourceArn")
+	} else if _, ok := f.getQueueByArn(*params.SourceArn); !ok {
+		return nil, resourceNotFound("SourceArn")
+	}
+	maxResults := 1
+	if params.MaxResults != nil {
+		if *params.MaxResults < 1 || *params.MaxResults > 10 {
+			return nil, invalidParameter("Value %d for parameter MaxResults is invalid. Reason: Must be between 1 "+
+				"and 10.", *params.MaxResults)
+		}
+		maxResults = int(*params.MaxResults)
+	}
+	output := &sqs.ListMessageMoveTasksOutput{}
+	for i := len(f.tasks) - 1; i >= 0 && len(output.Results) < maxResults; i-- {
+		task := f.tasks[i]
+		if task.sourceArn != *params.SourceArn {
+			continue
+		}
+		output.Results = append(output.Results, types.ListMessageMoveTasksResultEntry{
+			TaskHandle:                        aws.String(task.handle),
+			SourceArn:                         aws.String(task.sourceArn),
+			DestinationArn:                    task.destinationArn,
+			MaxNumberOfMessagesPerSecond:      task.maxPerSecond,
+			Status:                            aws.String(task.status),
+			StartedTimestamp:                  task.startedAt.UnixMilli(),
+			ApproximateNumberOfMessagesMoved:  task.moved,
+			ApproximateNumberOfMessagesToMove: aws.Int64(task.toMove),
+		})
+	}
+	return output, nil
+}
+
+// sendMessage must be called with the mutex locked, in FIFO queues, if the message is a duplicate, the message
+// previously sent is returned.
+func (f *Fake) sendMessage(q *queue, entry types.SendMessageBatchRequestEntry) (*message, error) {
+	now := f.now()
+	q.expireMessages(now)
+	body := aws.ToString(entry.MessageBody)
+	if body == "" {
+		return nil, missingParameter("MessageBody")
+	} else if !isValidMessageContents(body) {
+		return nil, &types.InvalidMessageContents{Message: aws.String("Invalid characters found. Valid unicode " +
+			"characters are #x9 | #xA | #xD | #x20 to #xD7FF | #xE000 to #xFFFD | #x10000 to #x10FFFF")}
+	}
+	err := validateMessageAttributes(entry.MessageAttributes)
+	if err != nil {
+		return nil, err
+	}
+	maxSize := q.getIntAttribute(types.QueueAttributeNameMaximumMessageSize)
+	if getMessageSize(body, entry.MessageAttributes) > maxSize {
+		return nil, invalidParameter("One or more parameters are invalid. Reason: Message must be shorter than %d "+
+			"bytes.", maxSize)
+	} else if entry.DelaySeconds < 0 || entry.DelaySeconds > maxDelaySeconds {
+		return nil, invalidParameter("Value %d for parameter DelaySeconds is invalid. Reason: DelaySeconds must be "+
+			">= 0 and <= %d.", entry.DelaySeconds, maxDelaySeconds)
+	}
+	delay := q.getDuration(types.QueueAttributeNameDelaySeconds)
+	if entry.DelaySeconds != 0 {
+		delay = time.Duration(entry.DelaySeconds) * time.Second
+	}
+	m := &message{
+		id:              util.NewUUID(),
+		body:            body,
+		attributes:      map[string]types.MessageAttributeValue{},
+		md5OfBody:       getMd5(body),
+		md5OfAttributes: getMd5OfMessageAttributes(entry.MessageAttributes),
+		sentAt:          now,
+		visibleAt:       now.Add(delay),
+	}
+	for name, value := range entry.MessageAttributes {
+		m.attributes[name] = value
+	}
+	for name, value := range entry.MessageSystemAttributes {
+		if name != string(types.MessageSystemAttributeNameForSendsAWSTraceHeader) {
+			return nil, invalidParameter("Message system attribute name '%s' is invalid.", name)
+		}
+		m.traceHeader = value.StringValue
+	}
+	if q.isFifo() {
+		if entry.DelaySeconds != 0 {
+			return nil, invalidParameter("Value %d for parameter DelaySeconds is invalid. Reason: The request "+
+				"include parameter that is not valid for this queue type.", entry.DelaySeconds)
+		} else if aws.ToString(entry.MessageGroupId) == "" {
+			return nil, missingParameter("MessageGroupId")
+		}
+		m.groupId = *entry.MessageGroupId
+		m.deduplicationId = aws.ToString(entry.MessageDeduplicationId)
+		if m.deduplicationId == "" && q.attributes[string(types.QueueAttributeNameContentBasedDeduplication)] != "true" {
+			return nil, invalidParameter("The queue should either have ContentBasedDeduplication enabled or " +
+				"MessageDeduplicationId provided explicitly")
+		} else if m.deduplicationId == "" {
+			sum := sha256.Sum256([]byte(body))
+			m.deduplicationId = hex.EncodeToString(sum[:])
+		}
+		key := m.deduplicationId
+		if q.attributes[string(types.QueueAttributeNameDeduplicationScope)] == "messageGroup" {
+			key = m.groupId + ":" + key
+		}
+		if entry, ok := q.deduplication[key]; ok && entry.expiresAt.After(now) {
+			return entry.message, nil
+		}
+		q.sequence++
+		m.sequenceNumber = fmt.Sprintf("%020d", q.sequence)
+		q.deduplication[key] = deduplicationEntry{message: m, expiresAt: now.Add(deduplicationInterval)}
+	} else if entry.MessageGroupId != nil || entry.MessageDeduplicationId != nil {
+		return nil, invalidParameter("The request include parameter that is not valid for this queue type.")
+	}
+	q.messages = append(q.messages, m)
+	f.wakeUp()
+	return m, nil
+}
+
+// receiveMessages must be called with the mutex locked.
+func (f *Fake) receiveMessages(q *queue, params *sqs.ReceiveMessageInput, maxNumberOfMessages int) []types.Message {
+	now := f.now()
+	q.expireMessages(now)
+	visibilityTimeout := q.getDuration(types.QueueAttributeNameVisibilityTimeout)
+	if params.VisibilityTimeout != 0 {
+		visibilityTimeout = time.Duration(params.VisibilityTimeout) * time.Second
+	}
+	fifo := q.isFifo()
+	lockedGroups := map[string]bool{}
+	for _, m := range q.messages {
+		if fifo && m.isInFlight(now) {
+			lockedGroups[m.groupId] = true
+		}
+	}
+	var result []types.Message
+	var remaining []*message
+	for _, m := range q.messages {
+		if len(result) == maxNumberOfMessages || m.visibleAt.After(now) || (fifo && lockedGroups[m.groupId]) {
+			if fifo && m.visibleAt.After(now) {
+				lockedGroups[m.groupId] = true
+			}
+			remaining = append(remaining, m)
+			continue
+		}
+		if q.redrive != nil && m.receiveCount >= q.redrive.maxReceiveCount {
+			if deadLetterQueue, ok := f.getQueueByArn(q.redrive.deadLetterTargetArn); ok {
+				f.moveMessage(m, deadLetterQueue, q.arn, now)
+				continue
+			}
+		}
+		m.receiveCount++
+		if m.firstReceivedAt.IsZero() {
+			m.firstReceivedAt = now
+		}
+		m.receiptHandle = base64.RawURLEncoding.EncodeToString([]byte(m.id + ":" + util.NewUUID()))
+		m.visibleAt = now.Add(visibilityTimeout)
+		result = append(result, m.toMessage(q, params.AttributeNames, params.MessageAttributeNames))
+		remaining = append(remaining, m)
+	}
+	q.messages = remaining
+	return result
+}
+
+// changeMessageVisibility must be called with the mutex locked.
+func (f *Fake) changeMessageVisibility(q *queue, receiptHandle string, visibilityTimeout int32) error {
+	m, err := q.getMessage(receiptHandle)
+	if err != nil {
+		return err
+	} else if visibilityTimeout < 0 || visibilityTimeout > maxVisibilityTimeout {
+		return invalidParameter("Value %d for parameter VisibilityTimeout is invalid. Reason: Must be between 0 "+
+			"and %d.", visibilityTimeout, maxVisibilityTimeout)
+	}
+	now := f.now()
+	if m == nil || m.receiptHandle != receiptHandle {
+		return invalidParameter("Value %s for parameter ReceiptHandle is invalid. Reason: Message does not exist "+
+			"or is not available for visibility timeout change.", receiptHandle)
+	} else if !m.isInFlight(now) {
+		return &types.MessageNotInflight{Message: aws.String("The message referred to isn't in flight.")}
+	}
+	m.visibleAt = now.Add(time.Duration(visibilityTimeout) * time.Second)
+	f.wakeUp()
+	return nil
+}
+
+// moveMessage must be called with the mutex locked, it appends the message to the destination queue as a new
+// message, visible and never received, the caller removes it from its current queue.
+func (f *Fake) moveMessage(m *message, destination *queue, deadLetterSourceArn string, now time.Time) {
+	m.deadLetterSourceArn = deadLetterSourceArn
+	m.receiveCount = 0
+	m.firstReceivedAt = time.Time{}
+	m.receiptHandle = ""
+	m.visibleAt = now
+	if destination.isFifo() {
+		destination.sequence++
+		m.sequenceNumber = fmt.Sprintf("%020d", destination.sequence)
+	}
+	destination.messages = append(destination.messages, m)
+	f.wakeUp()
+}
+
+// getMessage returns the message of the receipt handle, or nil if the message was deleted.
+func (q *queue) getMessage(receiptHandle string) (*message, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(receiptHandle)
+	id, _, ok := strings.Cut(string(decoded), ":")
+	if receiptHandle == "" || err != nil || !ok {
+		return nil, &types.ReceiptHandleIsInvalid{Message: aws.String(fmt.Sprint("The input receipt handle \"",
+			receiptHandle, "\" is not a valid receipt handle."))}
+	}
+	for _, m := range q.messages {
+		if m.id == id {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+func (q *queue) deleteMessage(receiptHandle string) error {
+	m, err := q.getMessage(receiptHandle)
+	if err != nil || m == nil || m.receiptHandle != receiptHandle {
+		return err
+	}
+	for i, v := range q.messages {
+		if v == m {
+			q.messages = append(q.messages[:i:i], q.messages[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// expireMessages removes the messages older than the MessageRetentionPeriod and the expired deduplication ids.
+func (q *queue) expireMessages(now time.Time) {
+	retention := q.getDuration(types.QueueAttributeNameMessageRetentionPeriod)
+	var remaining []*message
+	for _, m := range q.messages {
+		if m.sentAt.Add(retention).After(now) {
+			remaining = append(remaining, m)
+		}
+	}
+	q.messages = remaining
+	for key, entry := range q.deduplication {
+		if !entry.expiresAt.After(now) {
+			delete(q.deduplication, key)
+		}
+	}
+}
+
+func (m *message) isDelayed(now time.Time) bool {
+	return m.receiveCount == 0 && m.visibleAt.After(now)
+}
+
+func (m *message) isInFlight(now time.Time) bool {
+	return m.receiveCount > 0 && m.visibleAt.After(now)
+}
+
+func (m *message) toMessage(q *queue, attributeNames []types.QueueAttributeName,
+	messageAttributeNames []string) types.Message {
+	systemAttributes := map[string]string{
+		string(types.MessageSystemAttributeNameSenderId):                AccountId,
+		string(types.MessageSystemAttributeNameSentTimestamp):           toMillis(m.sentAt),
+		string(types.MessageSystemAttributeNameApproximateReceiveCount): strconv.Itoa(m.receiveCount),
+	}
+	if !m.firstReceivedAt.IsZero() {
+		systemAttributes[string(types.MessageSystemAttributeNameApproximateFirstReceiveTimestamp)] =
+			toMillis(m.firstReceivedAt)
+	}
+	if q.isFifo() {
+		systemAttributes[string(types.MessageSystemAttributeNameMessageGroupId)] = m.groupId
+		systemAttributes[string(types.MessageSystemAttributeNameMessageDeduplicationId)] = m.deduplicationId
+		systemAttributes[string(types.MessageSystemAttributeNameSequenceNumber)] = m.sequenceNumber
+	}
+	if m.traceHeader != nil {
+		systemAttributes[string(types.MessageSystemAttributeNameAWSTraceHeader)] = *m.traceHeader
+	}
+	if m.deadLetterSourceArn != "" {
+		systemAttributes[string(types.MessageSystemAttributeNameDeadLetterQueueSourceArn)] = m.deadLetterSourceArn
+	}
+	result := types.Message{
+		MessageId:     aws.String(m.id),
+		ReceiptHandle: aws.String(m.receiptHandle),
+		Body:          aws.String(m.body),
+		MD5OfBody:     aws.String(m.md5OfBody),
+	}
+	for name, value := range systemAttributes {
+		for _, attributeName := range attributeNames {
+			if attributeName == types.QueueAttributeNameAll || string(attributeName) == name {
+				if result.Attributes == nil {
+					result.Attributes = map[string]string{}
+				}
+				result.Attributes[name] = value
+				break
+			}
+		}
+	}
+	for name, value := range m.attributes {
+		if matchMessageAttributeName(name, messageAttributeNames) {
+			if result.MessageAttributes == nil {
+				result.MessageAttributes = map[string]types.MessageAttributeValue{}
+			}
+			result.MessageAttributes[name] = value
+		}
+	}
+	result.MD5OfMessageAttributes = toStringPointer(getMd5OfMessageAttributes(result.MessageAttributes))
+	return result
+}
+
+func matchMessageAttributeName(name string, names []string) bool {
+	for _, v := range names {
+		prefix, wildcard := strings.CutSuffix(v, ".*")
+		if v == "All" || v == ".*" || v == name || (wildcard && strings.HasPrefix(name, prefix+".")) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateMessageAttributes(attributes map[string]types.MessageAttributeValue) error {
+	if len(attributes) > maxMessageAttributes {
+		return invalidParameter("Number of message attributes [%d] exceeds the allowed maximum [%d].",
+			len(attributes), maxMessageAttributes)
+	}
+	for name, value := range attributes {
+		lowerName := strings.ToLower(name)
+		if !messageAttributeNameRegex.MatchString(name) || strings.HasPrefix(lowerName, "aws.") ||
+			strings.HasPrefix(lowerName, "amazon.") || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") ||
+			strings.Contains(name, "..") {
+			return invalidParameter("Message attribute name '%s' is invalid.", name)
+		}
+		dataType := aws.ToString(value.DataType)
+		baseDataType, _, _ := strings.Cut(dataType, ".")
+		switch baseDataType {
+		case "String", "Number":
+			if aws.ToString(value.StringValue) == "" {
+				return invalidParameter("Message (user) attribute '%s' must contain a non-empty value of type "+
+					"'%s'.", name, baseDataType)
+			} else if _, err := strconv.ParseFloat(*value.StringValue, 64); baseDataType == "Number" && err != nil {
+				return invalidParameter("Can't cast the value of message (user) attribute '%s' to a number.", name)
+			}
+		case "Binary":
+			if len(value.BinaryValue) == 0 {
+				return invalidParameter("Message (user) attribute '%s' must contain a non-empty value of type "+
+					"'Binary'.", name)
+			}
+		default:
+			return invalidParameter("The type of message (user) attribute '%s' is invalid. You must use only the "+
+				"following supported type prefixes: Binary, Number, String.", name)
+		}
+	}
+	return nil
+}
+
+func validateBatchEntryIds(ids []*string) error {
+	if len(ids) == 0 {
+		return &types.EmptyBatchRequest{Message: aws.String("There should be at least one entry in the request.")}
+	} else if len(ids) > maxBatchEntries {
+		return &types.TooManyEntriesInBatchRequest{Message: aws.String(fmt.Sprint("Maximum number of entries per ",
+			"request are ", maxBatchEntries, ". You have sent ", len(ids), "."))}
+	}
+	distinct := map[string]bool{}
+	for _, id := range ids {
+		if !batchEntryIdRegex.MatchString(aws.ToString(id)) {
+			return &types.InvalidBatchEntryId{Message: aws.String("A batch entry id can only contain alphanumeric " +
+				"characters, hyphens and underscores. It can be at most 80 letters long.")}
+		} else if distinct[*id] {
+			return &types.BatchEntryIdsNotDistinct{Message: aws.String(fmt.Sprint("Id ", *id, " repeated."))}
+		}
+		distinct[*id] = true
+	}
+	return nil
+}
+
+func newBatchResultErrorEntry(id *string, err error) types.BatchResultErrorEntry {
+	code := "InternalError"
+	message := err.Error()
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.ErrorCode()
+		message = apiErr.ErrorMessage()
+	}
+	return types.BatchResultErrorEntry{
+		Id:          id,
+		Code:        aws.String(code),
+		Message:     aws.String(message),
+		SenderFault: true,
+	}
+}
+
+func isValidMessageContents(body string) bool {
+	if !utf8.ValidString(body) {
+		return false
+	}
+	for _, r := range body {
+		if r != 0x9 && r != 0xA && r != 0xD && (r < 0x20 || (r > 0xD7FF && r < 0xE000) || r == 0xFFFE || r == 0xFFFF) {
+			return false
+		}
+	}
+	return true
+}
+
+func getMessageSize(body string, attributes map[string]types.MessageAttributeValue) int {
+	size := len(body)
+	for name, value := range attributes {
+		size += len(name) + len(aws.ToString(value.DataType)) + len(aws.ToString(value.StringValue)) +
+			len(value.BinaryValue)
+	}
+	return size
+}
+
+func getMd5(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// getMd5OfMessageAttributes calculates the MD5 of the message attributes with the algorithm of AWS SQS, each
+// attribute, sorted by name, is encoded as the name, the data type, the transport type and the value, with the length
+// of each part before it, or returns an empty string if there are no attributes.
+func getMd5OfMessageAttributes(attributes map[string]types.MessageAttributeValue) string {
+	if len(attributes) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := md5.New()
+	writeBytes := func(b []byte) {
+		_ = binary.Write(hash, binary.BigEndian, int32(len(b)))
+		hash.Write(b)
+	}
+	for _, name := range names {
+		value := attributes[name]
+		writeBytes([]byte(name))
+		writeBytes([]byte(aws.ToString(value.DataType)))
+		if value.BinaryValue != nil {
+			hash.Write([]byte{2})
+			writeBytes(value.BinaryValue)
+		} else {
+			hash.Write([]byte{1})
+			writeBytes([]byte(aws.ToString(value.StringValue)))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func toStringPointer(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func resourceNotFound(parameter string) error {
+	return &types.ResourceNotFoundException{Message: aws.String(fmt.Sprint("The resource that you specified for ",
+		"the ", parameter, " parameter doesn't exist."))}
+}
@@ -0,0 +1,528 @@
+package sqstest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxTagsPerQueue = 50
+
+var queueNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,80}$`)
+
+// attributes set by CreateQueue and SetQueueAttributes with their minimum and maximum values, if they are numeric.
+var settableAttributes = map[string][]int{
+	string(types.QueueAttributeNameDelaySeconds):                  {0, 900},
+	string(types.QueueAttributeNameMaximumMessageSize):            {1024, 262144},
+	string(types.QueueAttributeNameMessageRetentionPeriod):        {60, 1209600},
+	string(types.QueueAttributeNameReceiveMessageWaitTimeSeconds): {0, 20},
+	string(types.QueueAttributeNameVisibilityTimeout):             {0, 43200},
+	string(types.QueueAttributeNameKmsDataKeyReusePeriodSeconds):  {60, 86400},
+	string(types.QueueAttributeNamePolicy):                        nil,
+	string(types.QueueAttributeNameRedrivePolicy):                 nil,
+	string(types.QueueAttributeNameRedriveAllowPolicy):            nil,
+	string(types.QueueAttributeNameKmsMasterKeyId):                nil,
+	string(types.QueueAttributeNameSqsManagedSseEnabled):          nil,
+	string(types.QueueAttributeNameFifoQueue):                     nil,
+	string(types.QueueAttributeNameContentBasedDeduplication):     nil,
+	string(types.QueueAttributeNameDeduplicationScope):            nil,
+	string(types.QueueAttributeNameFifoThroughputLimit):           nil,
+}
+
+var fifoAttributeValues = map[string][]string{
+	string(types.QueueAttributeNameContentBasedDeduplication): {"true", "false"},
+	string(types.QueueAttributeNameDeduplicationScope):        {"messageGroup", "queue"},
+	string(types.QueueAttributeNameFifoThroughputLimit):       {"perQueue", "perMessageGroupId"},
+}
+
+var allAttributeNames = types.QueueAttributeNameAll.Values()
+
+type queue struct {
+	name          string
+	url           string
+	arn           string
+	attributes    map[string]string
+	tags          map[string]string
+	redrive       *redrivePolicy
+	createdAt     time.Time
+	modifiedAt    time.Time
+	messages      []*message
+	deduplication map[string]deduplicationEntry
+	sequence      int64
+}
+
+type redrivePolicy struct {
+	deadLetterTargetArn string
+	maxReceiveCount     int
+}
+
+// CreateQueue creates a standard or FIFO queue, if a queue with the same name already exists its URL is returned,
+// unless the attributes informed are different of the existing queue, returning *types.QueueNameExists.
+func (f *Fake) CreateQueue(_ context.Context, params *sqs.CreateQueueInput, _ ...func(*sqs.Options)) (
+	*sqs.CreateQueueOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if params.QueueName == nil {
+		return nil, missingParameter("QueueName")
+	}
+	q, err := f.createQueue(*params.QueueName, params.Attributes, params.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.CreateQueueOutput{QueueUrl: aws.String(q.url)}, nil
+}
+
+// DeleteQueue deletes the queue and its messages.
+func (f *Fake) DeleteQueue(_ context.Context, params *sqs.DeleteQueueInput, _ ...func(*sqs.Options)) (
+	*sqs.DeleteQueueOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	delete(f.queues, q.name)
+	return &sqs.DeleteQueueOutput{}, nil
+}
+
+// PurgeQueue deletes all messages of the queue, in flight or not.
+func (f *Fake) PurgeQueue(_ context.Context, params *sqs.PurgeQueueInput, _ ...func(*sqs.Options)) (
+	*sqs.PurgeQueueOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	q.messages = nil
+	return &sqs.PurgeQueueOutput{}, nil
+}
+
+// GetQueueUrl returns the URL of the queue by its name.
+func (f *Fake) GetQueueUrl(_ context.Context, params *sqs.GetQueueUrlInput, _ ...func(*sqs.Options)) (
+	*sqs.GetQueueUrlOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if params.QueueName == nil || *params.QueueName == "" {
+		return nil, missingParameter("QueueName")
+	}
+	if params.QueueOwnerAWSAccountId != nil && *params.QueueOwnerAWSAccountId != AccountId {
+		return nil, queueDoesNotExist()
+	}
+	q, ok := f.queues[*params.QueueName]
+	if !ok {
+		return nil, queueDoesNotExist()
+	}
+	return &sqs.GetQueueUrlOutput{QueueUrl: aws.String(q.url)}, nil
+}
+
+// GetQueueAttributes returns the attributes requested, including the approximate number of messages, or all of
+// them with types.QueueAttributeNameAll.
+func (f *Fake) GetQueueAttributes(_ context.Context, params *sqs.GetQueueAttributesInput, _ ...func(*sqs.Options)) (
+	*sqs.GetQueueAttributesOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range params.AttributeNames {
+		if !isAttributeName(name) {
+			return nil, &types.InvalidAttributeName{Message: aws.String(fmt.Sprint("Unknown Attribute ", name, "."))}
+		}
+	}
+	attributes := q.getAttributes(f.now())
+	result := map[string]string{}
+	for _, name := range params.AttributeNames {
+		if name == types.QueueAttributeNameAll {
+			return &sqs.GetQueueAttributesOutput{Attributes: attributes}, nil
+		}
+		if value, ok := attributes[string(name)]; ok {
+			result[string(name)] = value
+		}
+	}
+	return &sqs.GetQueueAttributesOutput{Attributes: result}, nil
+}
+
+// SetQueueAttributes changes the attributes of the queue, the type of the queue (FifoQueue) cannot be changed.
+func (f *Fake) SetQueueAttributes(_ context.Context, params *sqs.SetQueueAttributesInput, _ ...func(*sqs.Options)) (
+	*sqs.SetQueueAttributesOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.Attributes) == 0 {
+		return nil, missingParameter("Attributes")
+	}
+	if value, ok := params.Attributes[string(types.QueueAttributeNameFifoQueue)]; ok && value != q.attributes[string(
+		types.QueueAttributeNameFifoQueue)] && (value == "true" || q.isFifo()) {
+		return nil, invalidParameter("Invalid value for the parameter FifoQueue. Reason: Modifying queue type is " +
+			"not supported.")
+	}
+	redrive, err := f.validateAttributes(params.Attributes, q.isFifo())
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range params.Attributes {
+		if name != string(types.QueueAttributeNameFifoQueue) {
+			q.attributes[name] = value
+		}
+	}
+	if _, ok := params.Attributes[string(types.QueueAttributeNameRedrivePolicy)]; ok {
+		q.redrive = redrive
+	}
+	q.modifiedAt = f.now()
+	f.wakeUp()
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+// ListQueues returns the URLs of the queues in alphabetical order, filtered by the QueueNamePrefix, and paginated if
+// MaxResults is informed.
+func (f *Fake) ListQueues(_ context.Context, params *sqs.ListQueuesInput, _ ...func(*sqs.Options)) (
+	*sqs.ListQueuesOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var queueUrls []string
+	for _, q := range f.queues {
+		if strings.HasPrefix(q.name, aws.ToString(params.QueueNamePrefix)) {
+			queueUrls = append(queueUrls, q.url)
+		}
+	}
+	queueUrls, nextToken, err := paginate(queueUrls, params.MaxResults, params.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.ListQueuesOutput{QueueUrls: queueUrls, NextToken: nextToken}, nil
+}
+
+// ListDeadLetterSourceQueues returns the URLs of the queues whose RedrivePolicy targets the queue informed.
+func (f *Fake) ListDeadLetterSourceQueues(_ context.Context, params *sqs.ListDeadLetterSourceQueuesInput,
+	_ ...func(*sqs.Options)) (*sqs.ListDeadLetterSourceQueuesOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	queueUrls, nextToken, err := paginate(f.getDeadLetterSourceQueueUrls(q), params.MaxResults, params.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.ListDeadLetterSourceQueuesOutput{QueueUrls: queueUrls, NextToken: nextToken}, nil
+}
+
+// TagQueue adds or replaces the tags of the queue, up to 50 tags per queue.
+func (f *Fake) TagQueue(_ context.Context, params *sqs.TagQueueInput, _ ...func(*sqs.Options)) (
+	*sqs.TagQueueOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.Tags) == 0 {
+		return nil, missingParameter("Tags")
+	}
+	err = q.tag(params.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.TagQueueOutput{}, nil
+}
+
+// UntagQueue removes the tags of the queue by their keys.
+func (f *Fake) UntagQueue(_ context.Context, params *sqs.UntagQueueInput, _ ...func(*sqs.Options)) (
+	*sqs.UntagQueueOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.TagKeys) == 0 {
+		return nil, missingParameter("TagKeys")
+	}
+	for _, key := range params.TagKeys {
+		delete(q.tags, key)
+	}
+	return &sqs.UntagQueueOutput{}, nil
+}
+
+// ListQueueTags returns all tags of the queue.
+func (f *Fake) ListQueueTags(_ context.Context, params *sqs.ListQueueTagsInput, _ ...func(*sqs.Options)) (
+	*sqs.ListQueueTagsOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	for key, value := range q.tags {
+		tags[key] = value
+	}
+	return &sqs.ListQueueTagsOutput{Tags: tags}, nil
+}
+
+// createQueue must be called with the mutex locked.
+func (f *Fake) createQueue(name string, attributes, tags map[string]string) (*queue, error) {
+	fifo := attributes[string(types.QueueAttributeNameFifoQueue)] == "true"
+	baseName, hasFifoSuffix := strings.CutSuffix(name, ".fifo")
+	if !queueNameRegex.MatchString(baseName) || len(name) > 80 || fifo != hasFifoSuffix {
+		return nil, invalidParameter("Can only include alphanumeric characters, hyphens, or underscores. 1 to 80 " +
+			"in length. The name of a FIFO queue must end with the .fifo suffix.")
+	}
+	if q, ok := f.queues[name]; ok {
+		for key, value := range attributes {
+			if q.attributes[key] != value && (key != string(types.QueueAttributeNameFifoQueue) || value == "true") {
+				return nil, &types.QueueNameExists{Message: aws.String("A queue already exists with the same name " +
+					"and a different value for attribute " + key)}
+			}
+		}
+		return q, nil
+	}
+	redrive, err := f.validateAttributes(attributes, fifo)
+	if err != nil {
+		return nil, err
+	}
+	now := f.now()
+	q := &queue{
+		name:          name,
+		url:           f.endpoint + "/" + AccountId + "/" + name,
+		arn:           "arn:aws:sqs:" + Region + ":" + AccountId + ":" + name,
+		attributes:    getDefaultAttributes(fifo),
+		tags:          map[string]string{},
+		redrive:       redrive,
+		createdAt:     now,
+		modifiedAt:    now,
+		deduplication: map[string]deduplicationEntry{},
+	}
+	for key, value := range attributes {
+		if key != string(types.QueueAttributeNameFifoQueue) || fifo {
+			q.attributes[key] = value
+		}
+	}
+	err = q.tag(tags)
+	if err != nil {
+		return nil, err
+	}
+	f.queues[name] = q
+	return q, nil
+}
+
+// validateAttributes must be called with the mutex locked, it returns the parsed RedrivePolicy, nil if it is not
+// informed or empty.
+func (f *Fake) validateAttributes(attributes map[string]string, fifo bool) (*redrivePolicy, error) {
+	var redrive *redrivePolicy
+	for name, value := range attributes {
+		limits, ok := settableAttributes[name]
+		if !ok {
+			return nil, &types.InvalidAttributeName{Message: aws.String(fmt.Sprint("Unknown Attribute ", name, "."))}
+		}
+		if values, ok := fifoAttributeValues[name]; ok {
+			if !fifo {
+				return nil, invalidParameter("Unknown Attribute %s. Reason: only FIFO queues support it.", name)
+			} else if !containsString(values, value) {
+				return nil, invalidParameter("Invalid value for the parameter %s.", name)
+			}
+		}
+		switch name {
+		case string(types.QueueAttributeNameFifoQueue), string(types.QueueAttributeNameSqsManagedSseEnabled):
+			if value != "true" && value != "false" {
+				return nil, invalidParameter("Invalid value for the parameter %s.", name)
+			}
+		case string(types.QueueAttributeNameRedrivePolicy):
+			var err error
+			redrive, err = f.parseRedrivePolicy(value, fifo)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if limits != nil {
+			i, err := strconv.Atoi(value)
+			if err != nil || i < limits[0] || i > limits[1] {
+				return nil, invalidParameter("Invalid value for the parameter %s. Reason: must be between %d and %d.",
+					name, limits[0], limits[1])
+			}
+		}
+	}
+	return redrive, nil
+}
+
+// parseRedrivePolicy must be called with the mutex locked.
+func (f *Fake) parseRedrivePolicy(value string, fifo bool) (*redrivePolicy, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var policy struct {
+		DeadLetterTargetArn string      `json:"deadLetterTargetArn"`
+		MaxReceiveCount     json.Number `json:"maxReceiveCount"`
+	}
+	err := json.Unmarshal([]byte(value), &policy)
+	if err != nil {
+		return nil, invalidParameter("Invalid value for the parameter RedrivePolicy. Reason: Redrive policy is not " +
+			"a valid JSON map.")
+	}
+	maxReceiveCount, err := strconv.Atoi(policy.MaxReceiveCount.String())
+	if err != nil || maxReceiveCount < 1 || maxReceiveCount > 1000 {
+		return nil, invalidParameter("Value %s for parameter RedrivePolicy is invalid. Reason: Invalid value for "+
+			"maxReceiveCount: %s, valid values are from 1 to 1000 both inclusive.", value, policy.MaxReceiveCount)
+	}
+	target, ok := f.getQueueByArn(policy.DeadLetterTargetArn)
+	if !ok {
+		return nil, invalidParameter("Value %s for parameter RedrivePolicy is invalid. Reason: Dead letter target "+
+			"does not exist.", value)
+	} else if target.isFifo() != fifo {
+		return nil, invalidParameter("Value %s for parameter RedrivePolicy is invalid. Reason: Dead-letter queue "+
+			"must be same type of queue as the source.", value)
+	}
+	return &redrivePolicy{deadLetterTargetArn: policy.DeadLetterTargetArn, maxReceiveCount: maxReceiveCount}, nil
+}
+
+// getDeadLetterSourceQueueUrls must be called with the mutex locked.
+func (f *Fake) getDeadLetterSourceQueueUrls(q *queue) []string {
+	var queueUrls []string
+	for _, source := range f.queues {
+		if source.redrive != nil && source.redrive.deadLetterTargetArn == q.arn {
+			queueUrls = append(queueUrls, source.url)
+		}
+	}
+	return queueUrls
+}
+
+func (q *queue) isFifo() bool {
+	return q.attributes[string(types.QueueAttributeNameFifoQueue)] == "true"
+}
+
+func (q *queue) getIntAttribute(name types.QueueAttributeName) int {
+	i, _ := strconv.Atoi(q.attributes[string(name)])
+	return i
+}
+
+func (q *queue) getDuration(name types.QueueAttributeName) time.Duration {
+	return time.Duration(q.getIntAttribute(name)) * time.Second
+}
+
+func (q *queue) getAttributes(now time.Time) map[string]string {
+	q.expireMessages(now)
+	var visible, notVisible, delayed int
+	for _, m := range q.messages {
+		if m.isDelayed(now) {
+			delayed++
+		} else if m.isInFlight(now) {
+			notVisible++
+		} else {
+			visible++
+		}
+	}
+	result := map[string]string{
+		string(types.QueueAttributeNameQueueArn):                              q.arn,
+		string(types.QueueAttributeNameCreatedTimestamp):                      fmt.Sprint(q.createdAt.Unix()),
+		string(types.QueueAttributeNameLastModifiedTimestamp):                 fmt.Sprint(q.modifiedAt.Unix()),
+		string(types.QueueAttributeNameApproximateNumberOfMessages):           strconv.Itoa(visible),
+		string(types.QueueAttributeNameApproximateNumberOfMessagesNotVisible): strconv.Itoa(notVisible),
+		string(types.QueueAttributeNameApproximateNumberOfMessagesDelayed):    strconv.Itoa(delayed),
+	}
+	for name, value := range q.attributes {
+		if value != "" {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+func (q *queue) tag(tags map[string]string) error {
+	count := len(q.tags)
+	for key := range tags {
+		if _, ok := q.tags[key]; !ok {
+			count++
+		}
+	}
+	if count > maxTagsPerQueue {
+		return invalidParameter("Too many tags added for queue %s.", q.name)
+	}
+	for key, value := range tags {
+		q.tags[key] = value
+	}
+	return nil
+}
+
+func getDefaultAttributes(fifo bool) map[string]string {
+	result := map[string]string{
+		string(types.QueueAttributeNameDelaySeconds):                  "0",
+		string(types.QueueAttributeNameMaximumMessageSize):            "262144",
+		string(types.QueueAttributeNameMessageRetentionPeriod):        "345600",
+		string(types.QueueAttributeNameReceiveMessageWaitTimeSeconds): "0",
+		string(types.QueueAttributeNameVisibilityTimeout):             "30",
+		string(types.QueueAttributeNameSqsManagedSseEnabled):          "true",
+	}
+	if fifo {
+		result[string(types.QueueAttributeNameFifoQueue)] = "true"
+		result[string(types.QueueAttributeNameContentBasedDeduplication)] = "false"
+		result[string(types.QueueAttributeNameDeduplicationScope)] = "queue"
+		result[string(types.QueueAttributeNameFifoThroughputLimit)] = "perQueue"
+	}
+	return result
+}
+
+func isAttributeName(name types.QueueAttributeName) bool {
+	for _, v := range allAttributeNames {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// paginate sorts the values and returns the page after the nextToken with up to maxResults values, and the token of
+// the next page, if there is one.
+func paginate(values []string, maxResults *int32, nextToken *string) ([]string, *string, error) {
+	sort.Strings(values)
+	if nextToken != nil && *nextToken != "" {
+		last, err := base64.StdEncoding.DecodeString(*nextToken)
+		if err != nil {
+			return nil, nil, invalidParameter("Invalid NextToken value.")
+		}
+		i := sort.SearchStrings(values, string(last))
+		if i < len(values) && values[i] == string(last) {
+			i++
+		}
+		values = values[i:]
+	}
+	limit := 1000
+	if maxResults != nil {
+		if *maxResults < 1 || *maxResults > 1000 {
+			return nil, nil, invalidParameter("Value %d for parameter MaxResults is invalid. Reason: must be "+
+				"between 1 and 1000.", *maxResults)
+		}
+		limit = int(*maxResults)
+	}
+	if len(values) <= limit {
+		return values, nil, nil
+	}
+	values = values[:limit]
+	if maxResults == nil {
+		return values, nil, nil
+	}
+	return values, aws.String(base64.StdEncoding.EncodeToString([]byte(values[limit-1]))), nil
+}
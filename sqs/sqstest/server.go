@@ -0,0 +1,163 @@
+package sqstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/smithy-go"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// TargetPrefix is the prefix of the X-Amz-Target header of the requests of the AWS JSON 1.0 protocol of SQS,
+// followed by the name of the operation, like AmazonSQS.SendMessage.
+const TargetPrefix = "AmazonSQS."
+
+// ContentType is the content type of the requests and responses of the AWS JSON 1.0 protocol.
+const ContentType = "application/x-amz-json-1.0"
+
+// Server is a local HTTP server speaking the AWS JSON 1.0 protocol of SQS, used by the AWS SDK from sqs v1.29, backed
+// by a Fake. Any SDK client, including the default client of the sqs package, can call it through the BaseEndpoint,
+// exercising the serialization, checksums and errors of the SDK end to end, without LocalStack or network:
+//
+//	server := sqstest.NewServer()
+//	defer server.Close()
+//	client := sqs.NewClient(server.Config())
+//
+// The queue URLs created by the server use its URL as endpoint.
+type Server struct {
+	*httptest.Server
+	// Fake where the queues and messages are stored, it can be used directly to set up and inspect the queues.
+	Fake *Fake
+}
+
+// listDeadLetterSourceQueuesOutput is the output of ListDeadLetterSourceQueues with the member names of the protocol,
+// which differ from the field names of the SDK only in this operation.
+type listDeadLetterSourceQueuesOutput struct {
+	QueueUrls []string `json:"queueUrls"`
+	NextToken *string  `json:"NextToken,omitempty"`
+}
+
+type operation func(ctx context.Context, f *Fake, body []byte) (any, error)
+
+var operations = map[string]operation{
+	"SendMessage":                  newOperation((*Fake).SendMessage),
+	"SendMessageBatch":             newOperation((*Fake).SendMessageBatch),
+	"ReceiveMessage":               newOperation((*Fake).ReceiveMessage),
+	"DeleteMessage":                newOperation((*Fake).DeleteMessage),
+	"DeleteMessageBatch":           newOperation((*Fake).DeleteMessageBatch),
+	"ChangeMessageVisibility":      newOperation((*Fake).ChangeMessageVisibility),
+	"ChangeMessageVisibilityBatch": newOperation((*Fake).ChangeMessageVisibilityBatch),
+	"StartMessageMoveTask":         newOperation((*Fake).StartMessageMoveTask),
+	"CancelMessageMoveTask":        newOperation((*Fake).CancelMessageMoveTask),
+	"ListMessageMoveTasks":         newOperation((*Fake).ListMessageMoveTasks),
+	"CreateQueue":                  newOperation((*Fake).CreateQueue),
+	"DeleteQueue":                  newOperation((*Fake).DeleteQueue),
+	"PurgeQueue":                   newOperation((*Fake).PurgeQueue),
+	"GetQueueUrl":                  newOperation((*Fake).GetQueueUrl),
+	"GetQueueAttributes":           newOperation((*Fake).GetQueueAttributes),
+	"SetQueueAttributes":           newOperation((*Fake).SetQueueAttributes),
+	"ListQueues":                   newOperation((*Fake).ListQueues),
+	"ListDeadLetterSourceQueues":   newOperation(listDeadLetterSourceQueues),
+	"TagQueue":                     newOperation((*Fake).TagQueue),
+	"UntagQueue":                   newOperation((*Fake).UntagQueue),
+	"ListQueueTags":                newOperation((*Fake).ListQueueTags),
+}
+
+// NewServer starts a Server with an empty Fake, call Close when the test finishes.
+func NewServer() *Server {
+	fake := NewFake()
+	server := &Server{Server: httptest.NewServer(NewHandler(fake)), Fake: fake}
+	fake.SetEndpoint(server.URL)
+	return server
+}
+
+// NewHandler returns the http.Handler of the AWS JSON 1.0 protocol of SQS backed by the fake informed, to be served
+// by your own server, the URLs of the queues use the endpoint of the fake (see Fake.SetEndpoint).
+func NewHandler(fake *Fake) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.Header.Get("X-Amz-Target")
+		op, ok := operations[strings.TrimPrefix(target, TargetPrefix)]
+		if r.Method != http.MethodPost || !strings.HasPrefix(target, TargetPrefix) || !ok {
+			writeError(w, &smithy.GenericAPIError{
+				Code:    "UnknownOperationException",
+				Message: "Operation " + target + " is not supported.",
+				Fault:   smithy.FaultClient,
+			})
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		output, err := op(r.Context(), fake, body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		_ = json.NewEncoder(w).Encode(output)
+	})
+}
+
+// Config returns an aws.Config that calls the server, with the Region of the Fake and static credentials.
+func (s *Server) Config() aws.Config {
+	return aws.Config{
+		Region:       Region,
+		BaseEndpoint: aws.String(s.URL),
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test", Source: "sqstest"}, nil
+		}),
+	}
+}
+
+func newOperation[Input, Output any](
+	method func(f *Fake, ctx context.Context, params *Input, optFns ...func(*sqs.Options)) (*Output, error),
+) operation {
+	return func(ctx context.Context, f *Fake, body []byte) (any, error) {
+		input := new(Input)
+		err := json.Unmarshal(body, input)
+		if err != nil {
+			return nil, &smithy.GenericAPIError{
+				Code:    "SerializationException",
+				Message: err.Error(),
+				Fault:   smithy.FaultClient,
+			}
+		}
+		return method(f, ctx, input)
+	}
+}
+
+func listDeadLetterSourceQueues(f *Fake, ctx context.Context, params *sqs.ListDeadLetterSourceQueuesInput,
+	optFns ...func(*sqs.Options)) (*listDeadLetterSourceQueuesOutput, error) {
+	output, err := f.ListDeadLetterSourceQueues(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	return &listDeadLetterSourceQueuesOutput{QueueUrls: output.QueueUrls, NextToken: output.NextToken}, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := "InternalFailure"
+	message := err.Error()
+	status := http.StatusInternalServerError
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.ErrorCode()
+		message = apiErr.ErrorMessage()
+		if apiErr.ErrorFault() != smithy.FaultServer {
+			status = http.StatusBadRequest
+		}
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"__type":  "com.amazonaws.sqs#" + code,
+		"message": message,
+	})
+}
@@ -0,0 +1,189 @@
+package sqstest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := sqs.NewFromConfig(server.Config())
+	ctx := context.TODO()
+	dlqOutput, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String("server-dlq")})
+	if err != nil {
+		t.Fatalf("CreateQueue() error = %v", err)
+	}
+	dlqArn := server.Fake.QueueArn(*dlqOutput.QueueUrl)
+	output, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("server"),
+		Attributes: map[string]string{
+			"RedrivePolicy": `{"deadLetterTargetArn":"` + dlqArn + `","maxReceiveCount":"5"}`,
+		},
+		Tags: map[string]string{"team": "test"},
+	})
+	if err != nil {
+		t.Fatalf("CreateQueue() error = %v", err)
+	}
+	queueUrl := output.QueueUrl
+	if !strings.HasPrefix(*queueUrl, server.URL) {
+		t.Errorf("CreateQueue() url = %s, want prefix %s", *queueUrl, server.URL)
+	}
+	_, err = client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    queueUrl,
+		MessageBody: aws.String(`{"name":"Test Name"}`),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"text":  {DataType: aws.String("String"), StringValue: aws.String("text test")},
+			"bytes": {DataType: aws.String("Binary"), BinaryValue: []byte("bytes test")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	batchOutput, err := client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: queueUrl,
+		Entries: []types.SendMessageBatchRequestEntry{
+			{Id: aws.String("1"), MessageBody: aws.String("batch 1")},
+			{Id: aws.String("2"), MessageBody: aws.String("batch 2")},
+		},
+	})
+	if err != nil || len(batchOutput.Successful) != 2 {
+		t.Fatalf("SendMessageBatch() output = %+v, err = %v", batchOutput, err)
+	}
+	receiveOutput, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:              queueUrl,
+		AttributeNames:        []types.QueueAttributeName{types.QueueAttributeNameAll},
+		MessageAttributeNames: []string{"All"},
+		MaxNumberOfMessages:   10,
+		WaitTimeSeconds:       1,
+	})
+	if err != nil || len(receiveOutput.Messages) != 3 {
+		t.Fatalf("ReceiveMessage() output = %+v, err = %v", receiveOutput, err)
+	}
+	first := receiveOutput.Messages[0]
+	if !bytes.Equal(first.MessageAttributes["bytes"].BinaryValue, []byte("bytes test")) ||
+		first.Attributes["ApproximateReceiveCount"] != "1" {
+		t.Errorf("ReceiveMessage() message = %+v, want attributes", first)
+	}
+	_, err = client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          queueUrl,
+		ReceiptHandle:     first.ReceiptHandle,
+		VisibilityTimeout: 60,
+	})
+	if err != nil {
+		t.Errorf("ChangeMessageVisibility() error = %v", err)
+	}
+	changeBatchOutput, err := client.ChangeMessageVisibilityBatch(ctx, &sqs.ChangeMessageVisibilityBatchInput{
+		QueueUrl: queueUrl,
+		Entries: []types.ChangeMessageVisibilityBatchRequestEntry{
+			{Id: aws.String("1"), ReceiptHandle: receiveOutput.Messages[1].ReceiptHandle, VisibilityTimeout: 60},
+		},
+	})
+	if err != nil || len(changeBatchOutput.Successful) != 1 {
+		t.Errorf("ChangeMessageVisibilityBatch() output = %+v, err = %v", changeBatchOutput, err)
+	}
+	_, err = client.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: queueUrl, ReceiptHandle: first.ReceiptHandle})
+	if err != nil {
+		t.Errorf("DeleteMessage() error = %v", err)
+	}
+	deleteBatchOutput, err := client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: queueUrl,
+		Entries: []types.DeleteMessageBatchRequestEntry{
+			{Id: aws.String("2"), ReceiptHandle: receiveOutput.Messages[1].ReceiptHandle},
+			{Id: aws.String("3"), ReceiptHandle: receiveOutput.Messages[2].ReceiptHandle},
+		},
+	})
+	if err != nil || len(deleteBatchOutput.Successful) != 2 {
+		t.Errorf("DeleteMessageBatch() output = %+v, err = %v", deleteBatchOutput, err)
+	}
+	attributesOutput, err := client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       queueUrl,
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameAll},
+	})
+	if err != nil || attributesOutput.Attributes["ApproximateNumberOfMessages"] != "0" ||
+		attributesOutput.Attributes["ApproximateNumberOfMessagesNotVisible"] != "0" {
+		t.Errorf("GetQueueAttributes() output = %+v, err = %v", attributesOutput, err)
+	}
+	_, err = client.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl:   queueUrl,
+		Attributes: map[string]string{"DelaySeconds": "1"},
+	})
+	if err != nil {
+		t.Errorf("SetQueueAttributes() error = %v", err)
+	}
+	_, err = client.TagQueue(ctx, &sqs.TagQueueInput{QueueUrl: queueUrl, Tags: map[string]string{"env": "test"}})
+	if err != nil {
+		t.Errorf("TagQueue() error = %v", err)
+	}
+	_, err = client.UntagQueue(ctx, &sqs.UntagQueueInput{QueueUrl: queueUrl, TagKeys: []string{"team"}})
+	if err != nil {
+		t.Errorf("UntagQueue() error = %v", err)
+	}
+	tagsOutput, err := client.ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: queueUrl})
+	if err != nil || len(tagsOutput.Tags) != 1 || tagsOutput.Tags["env"] != "test" {
+		t.Errorf("ListQueueTags() output = %+v, err = %v", tagsOutput, err)
+	}
+	urlOutput, err := client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String("server")})
+	if err != nil || aws.ToString(urlOutput.QueueUrl) != *queueUrl {
+		t.Errorf("GetQueueUrl() output = %+v, err = %v", urlOutput, err)
+	}
+	listOutput, err := client.ListQueues(ctx, &sqs.ListQueuesInput{QueueNamePrefix: aws.String("server")})
+	if err != nil || len(listOutput.QueueUrls) != 2 {
+		t.Errorf("ListQueues() output = %+v, err = %v", listOutput, err)
+	}
+	sourcesOutput, err := client.ListDeadLetterSourceQueues(ctx, &sqs.ListDeadLetterSourceQueuesInput{
+		QueueUrl: dlqOutput.QueueUrl,
+	})
+	if err != nil || len(sourcesOutput.QueueUrls) != 1 {
+		t.Errorf("ListDeadLetterSourceQueues() output = %+v, err = %v", sourcesOutput, err)
+	}
+	taskOutput, err := client.StartMessageMoveTask(ctx, &sqs.StartMessageMoveTaskInput{SourceArn: &dlqArn})
+	if err != nil {
+		t.Fatalf("StartMessageMoveTask() error = %v", err)
+	}
+	tasksOutput, err := client.ListMessageMoveTasks(ctx, &sqs.ListMessageMoveTasksInput{SourceArn: &dlqArn})
+	if err != nil || len(tasksOutput.Results) != 1 ||
+		aws.ToString(tasksOutput.Results[0].TaskHandle) != aws.ToString(taskOutput.TaskHandle) {
+		t.Errorf("ListMessageMoveTasks() output = %+v, err = %v", tasksOutput, err)
+	}
+	_, err = client.CancelMessageMoveTask(ctx, &sqs.CancelMessageMoveTaskInput{TaskHandle: taskOutput.TaskHandle})
+	var unsupportedErr *types.UnsupportedOperation
+	if !errors.As(err, &unsupportedErr) {
+		t.Errorf("CancelMessageMoveTask() error = %v, want *types.UnsupportedOperation", err)
+	}
+	_, err = client.PurgeQueue(ctx, &sqs.PurgeQueueInput{QueueUrl: queueUrl})
+	if err != nil {
+		t.Errorf("PurgeQueue() error = %v", err)
+	}
+	_, err = client.DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: queueUrl})
+	if err != nil {
+		t.Errorf("DeleteQueue() error = %v", err)
+	}
+	_, err = client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String("server")})
+	var queueErr *types.QueueDoesNotExist
+	if !errors.As(err, &queueErr) {
+		t.Errorf("GetQueueUrl() error = %v, want *types.QueueDoesNotExist", err)
+	}
+}
+
+func TestServerUnknownOperation(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	request, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("{}"))
+	request.Header.Set("X-Amz-Target", TargetPrefix+"AddPermission")
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Do() status = %d, want %d", response.StatusCode, http.StatusBadRequest)
+	}
+}
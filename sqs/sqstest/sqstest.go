@@ -0,0 +1,187 @@
+// Package sqstest provides an in-memory implementation of the AWS SQS API for unit tests, so that the producers,
+// consumers, queue and message functions of the sqs package can be exercised without AWS credentials, network or
+// real queues:
+//
+//	fake := sqstest.NewFake()
+//	queueUrl := fake.NewQueue("orders", nil)
+//	client := sqs.NewClientFromAPI(fake)
+//	_, err := client.SendMessage(ctx, queueUrl, order)
+//
+// The Fake follows the AWS SQS semantics that matter to the library: standard and FIFO queues (message groups,
+// deduplication and sequence numbers), visibility timeouts, delays, long polling, receive counts, redrive to a
+// dead-letter queue, message move tasks, queue attributes and tags. The errors returned are the same AWS API errors
+// of the SDK, like *types.QueueDoesNotExist, so they can be checked with errors.As.
+//
+// Limits that only protect the AWS service, like the throughput of the queues or the interval between purges, are not
+// enforced.
+package sqstest
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Region is the AWS region used in the queue URLs and ARNs created by the Fake.
+const Region = "us-east-1"
+
+// AccountId is the AWS account id used in the queue URLs and ARNs created by the Fake.
+const AccountId = "000000000000"
+
+// DefaultEndpoint is the endpoint used in the queue URLs created by the Fake.
+const DefaultEndpoint = "https://sqs." + Region + ".amazonaws.com"
+
+// Fake is an in-memory AWS SQS, it implements the sqs.API interface, so it can be passed to sqs.NewClientFromAPI.
+// It's safe for concurrent use and must be created with NewFake.
+type Fake struct {
+	mutex    sync.Mutex
+	now      func() time.Time
+	endpoint string
+	queues   map[string]*queue
+	tasks    []*moveTask
+	notify   chan struct{}
+}
+
+// NewFake creates an empty Fake, without queues, using the system clock.
+func NewFake() *Fake {
+	return &Fake{
+		now:      time.Now,
+		endpoint: DefaultEndpoint,
+		queues:   map[string]*queue{},
+		notify:   make(chan struct{}),
+	}
+}
+
+// SetClock replaces the clock used for visibility timeouts, delays, retention and deduplication, allowing the tests
+// to advance the time without sleeping. The long polling of ReceiveMessage keeps waiting in real time.
+func (f *Fake) SetClock(now func() time.Time) *Fake {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = now
+	return f
+}
+
+// SetEndpoint replaces the endpoint used in the URLs of the queues created after the call, the queues are always
+// found by the account id and name in the path of the URL, regardless of the endpoint.
+func (f *Fake) SetEndpoint(endpoint string) *Fake {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.endpoint = strings.TrimSuffix(endpoint, "/")
+	return f
+}
+
+// NewQueue creates the queue with the attributes informed, like in CreateQueue, and returns its URL. It panics if
+// the queue cannot be created, as it's meant to be used in the setup of the tests.
+func (f *Fake) NewQueue(name string, attributes map[string]string) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.createQueue(name, attributes, nil)
+	if err != nil {
+		panic(fmt.Sprint("sqstest: error creating queue ", name, ": ", err))
+	}
+	return q.url
+}
+
+// QueueArn returns the ARN of the queue of the URL informed, useful to configure a RedrivePolicy, or an empty string
+// if the queue does not exist.
+func (f *Fake) QueueArn(queueUrl string) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(&queueUrl)
+	if err != nil {
+		return ""
+	}
+	return q.arn
+}
+
+// Messages returns a snapshot of all messages stored in the queue of the URL informed, visible or not, in the order
+// they were sent, with the same fields of a received message, but without receipt handle. The receive count and
+// visibility of the messages are not changed.
+func (f *Fake) Messages(queueUrl string) []types.Message {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	q, err := f.getQueue(&queueUrl)
+	if err != nil {
+		return nil
+	}
+	q.expireMessages(f.now())
+	result := make([]types.Message, len(q.messages))
+	for i, m := range q.messages {
+		result[i] = m.toMessage(q, allAttributeNames, []string{"All"})
+		result[i].ReceiptHandle = nil
+	}
+	return result
+}
+
+// getQueue must be called with the mutex locked.
+func (f *Fake) getQueue(queueUrl *string) (*queue, error) {
+	if queueUrl == nil || *queueUrl == "" {
+		return nil, missingParameter("QueueUrl")
+	}
+	name, ok := parseQueueUrl(*queueUrl)
+	if !ok {
+		return nil, queueDoesNotExist()
+	}
+	q, ok := f.queues[name]
+	if !ok {
+		return nil, queueDoesNotExist()
+	}
+	return q, nil
+}
+
+// getQueueByArn must be called with the mutex locked.
+func (f *Fake) getQueueByArn(arn string) (*queue, bool) {
+	prefix := "arn:aws:sqs:" + Region + ":" + AccountId + ":"
+	if !strings.HasPrefix(arn, prefix) {
+		return nil, false
+	}
+	q, ok := f.queues[strings.TrimPrefix(arn, prefix)]
+	return q, ok
+}
+
+// wakeUp must be called with the mutex locked, it wakes up the long polls waiting for messages.
+func (f *Fake) wakeUp() {
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+func parseQueueUrl(queueUrl string) (string, bool) {
+	u, err := url.Parse(queueUrl)
+	if err != nil {
+		return "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] != AccountId || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func toMillis(t time.Time) string {
+	return fmt.Sprint(t.UnixMilli())
+}
+
+func queueDoesNotExist() error {
+	return &types.QueueDoesNotExist{Message: aws.String("The specified queue does not exist.")}
+}
+
+func invalidParameter(format string, args ...any) error {
+	return &smithy.GenericAPIError{
+		Code:    "InvalidParameterValue",
+		Message: fmt.Sprintf(format, args...),
+		Fault:   smithy.FaultClient,
+	}
+}
+
+func missingParameter(name string) error {
+	return &smithy.GenericAPIError{
+		Code:    "MissingParameter",
+		Message: fmt.Sprint("The request must contain the parameter ", name, "."),
+		Fault:   smithy.FaultClient,
+	}
+}
@@ -0,0 +1,388 @@
+package sqstest
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+type testFakeError struct {
+	name     string
+	call     func(f *Fake, queueUrl string) error
+	wantCode string
+}
+
+func (c *testClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestFakeStandardQueue(t *testing.T) {
+	f, clock := initFakeWithClock()
+	queueUrl := f.NewQueue("standard", map[string]string{"VisibilityTimeout": "10"})
+	ctx := context.TODO()
+	sendOutput, err := f.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    &queueUrl,
+		MessageBody: aws.String("body test"),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"name": {DataType: aws.String("String"), StringValue: aws.String("value")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	messages := receive(t, f, queueUrl, 10)
+	if len(messages) != 1 || aws.ToString(messages[0].MessageId) != aws.ToString(sendOutput.MessageId) ||
+		aws.ToString(messages[0].MD5OfBody) != aws.ToString(sendOutput.MD5OfMessageBody) ||
+		aws.ToString(messages[0].MD5OfMessageAttributes) != aws.ToString(sendOutput.MD5OfMessageAttributes) ||
+		messages[0].Attributes["ApproximateReceiveCount"] != "1" {
+		t.Fatalf("ReceiveMessage() messages = %+v, want the message sent", messages)
+	}
+	if messages = receive(t, f, queueUrl, 10); len(messages) != 0 {
+		t.Errorf("ReceiveMessage() messages = %+v, want none while in flight", messages)
+	}
+	clock.Advance(10 * time.Second)
+	messages = receive(t, f, queueUrl, 10)
+	if len(messages) != 1 || messages[0].Attributes["ApproximateReceiveCount"] != "2" {
+		t.Fatalf("ReceiveMessage() messages = %+v, want the message visible again", messages)
+	}
+	_, err = f.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: &queueUrl, ReceiptHandle: messages[0].ReceiptHandle})
+	if err != nil {
+		t.Errorf("DeleteMessage() error = %v", err)
+	}
+	clock.Advance(10 * time.Second)
+	if len(f.Messages(queueUrl)) != 0 {
+		t.Errorf("DeleteMessage() messages = %+v, want none", f.Messages(queueUrl))
+	}
+}
+
+func TestFakeVisibilityAndDelay(t *testing.T) {
+	f, clock := initFakeWithClock()
+	queueUrl := f.NewQueue("delay", map[string]string{"DelaySeconds": "5"})
+	ctx := context.TODO()
+	for _, delay := range []int32{0, 30} {
+		_, err := f.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:     &queueUrl,
+			MessageBody:  aws.String("body test"),
+			DelaySeconds: delay,
+		})
+		if err != nil {
+			t.Fatalf("SendMessage() error = %v", err)
+		}
+	}
+	attributes := getAttributes(t, f, queueUrl)
+	if attributes["ApproximateNumberOfMessagesDelayed"] != "2" {
+		t.Errorf("GetQueueAttributes() attributes = %v, want 2 delayed", attributes)
+	}
+	clock.Advance(5 * time.Second)
+	messages := receive(t, f, queueUrl, 10)
+	if len(messages) != 1 {
+		t.Fatalf("ReceiveMessage() messages = %+v, want only the message of the queue delay", messages)
+	}
+	_, err := f.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &queueUrl,
+		ReceiptHandle:     messages[0].ReceiptHandle,
+		VisibilityTimeout: 0,
+	})
+	if err != nil {
+		t.Errorf("ChangeMessageVisibility() error = %v", err)
+	}
+	if messages = receive(t, f, queueUrl, 10); len(messages) != 1 {
+		t.Fatalf("ReceiveMessage() messages = %+v, want the message visible again", messages)
+	}
+	attributes = getAttributes(t, f, queueUrl)
+	if attributes["ApproximateNumberOfMessagesNotVisible"] != "1" ||
+		attributes["ApproximateNumberOfMessagesDelayed"] != "1" {
+		t.Errorf("GetQueueAttributes() attributes = %v, want 1 not visible and 1 delayed", attributes)
+	}
+	_, err = f.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:      &queueUrl,
+		ReceiptHandle: aws.String("invalid"),
+	})
+	var receiptHandleErr *types.ReceiptHandleIsInvalid
+	if !errors.As(err, &receiptHandleErr) {
+		t.Errorf("ChangeMessageVisibility() error = %v, want *types.ReceiptHandleIsInvalid", err)
+	}
+}
+
+func TestFakeFifoQueue(t *testing.T) {
+	f, _ := initFakeWithClock()
+	queueUrl := f.NewQueue("fifo.fifo", map[string]string{"FifoQueue": "true", "ContentBasedDeduplication": "true"})
+	ctx := context.TODO()
+	var messageIds []string
+	for _, body := range []string{"a1", "a2", "b1", "a1"} {
+		output, err := f.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:       &queueUrl,
+			MessageBody:    aws.String(body),
+			MessageGroupId: aws.String(body[:1]),
+		})
+		if err != nil {
+			t.Fatalf("SendMessage() error = %v", err)
+		}
+		messageIds = append(messageIds, aws.ToString(output.MessageId))
+	}
+	if messageIds[0] != messageIds[3] || len(f.Messages(queueUrl)) != 3 {
+		t.Errorf("SendMessage() duplicate message ids = %v, messages = %d, want deduplicated", messageIds,
+			len(f.Messages(queueUrl)))
+	}
+	messages := receive(t, f, queueUrl, 1)
+	if len(messages) != 1 || aws.ToString(messages[0].Body) != "a1" {
+		t.Fatalf("ReceiveMessage() messages = %+v, want a1", messages)
+	}
+	messages = receive(t, f, queueUrl, 10)
+	if len(messages) != 1 || aws.ToString(messages[0].Body) != "b1" {
+		t.Errorf("ReceiveMessage() messages = %+v, want b1 while group a is in flight", messages)
+	}
+	_, err := f.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: &queueUrl, MessageBody: aws.String("c1")})
+	if err == nil {
+		t.Error("SendMessage() without MessageGroupId error = nil, want error")
+	}
+}
+
+func TestFakeRedrive(t *testing.T) {
+	f, clock := initFakeWithClock()
+	dlqUrl := f.NewQueue("redrive-dlq", nil)
+	queueUrl := f.NewQueue("redrive", map[string]string{
+		"VisibilityTimeout": "1",
+		"RedrivePolicy":     `{"deadLetterTargetArn":"` + f.QueueArn(dlqUrl) + `","maxReceiveCount":"2"}`,
+	})
+	ctx := context.TODO()
+	_, err := f.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: &queueUrl, MessageBody: aws.String("body test")})
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		messages := receive(t, f, queueUrl, 1)
+		if (i < 2) != (len(messages) == 1) {
+			t.Fatalf("ReceiveMessage() attempt %d messages = %+v", i+1, messages)
+		}
+		clock.Advance(time.Second)
+	}
+	dlqMessages := f.Messages(dlqUrl)
+	if len(dlqMessages) != 1 || dlqMessages[0].Attributes["DeadLetterQueueSourceArn"] != f.QueueArn(queueUrl) {
+		t.Fatalf("Messages() dead-letter queue = %+v, want the message redriven", dlqMessages)
+	}
+	sources, err := f.ListDeadLetterSourceQueues(ctx, &sqs.ListDeadLetterSourceQueuesInput{QueueUrl: &dlqUrl})
+	if err != nil || len(sources.QueueUrls) != 1 || sources.QueueUrls[0] != queueUrl {
+		t.Errorf("ListDeadLetterSourceQueues() output = %v, err = %v", sources, err)
+	}
+	sourceArn := f.QueueArn(dlqUrl)
+	_, err = f.StartMessageMoveTask(ctx, &sqs.StartMessageMoveTaskInput{SourceArn: &sourceArn})
+	if err != nil {
+		t.Fatalf("StartMessageMoveTask() error = %v", err)
+	}
+	tasks, err := f.ListMessageMoveTasks(ctx, &sqs.ListMessageMoveTasksInput{SourceArn: &sourceArn})
+	if err != nil || len(tasks.Results) != 1 || tasks.Results[0].ApproximateNumberOfMessagesMoved != 1 {
+		t.Errorf("ListMessageMoveTasks() output = %+v, err = %v", tasks, err)
+	}
+	if messages := receive(t, f, queueUrl, 1); len(messages) != 1 {
+		t.Errorf("ReceiveMessage() messages = %+v, want the message moved back", messages)
+	}
+}
+
+func TestFakeLongPolling(t *testing.T) {
+	f := NewFake()
+	queueUrl := f.NewQueue("long-polling", nil)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = f.SendMessage(context.TODO(), &sqs.SendMessageInput{
+			QueueUrl:    &queueUrl,
+			MessageBody: aws.String("body test"),
+		})
+	}()
+	start := time.Now()
+	output, err := f.ReceiveMessage(context.TODO(), &sqs.ReceiveMessageInput{QueueUrl: &queueUrl, WaitTimeSeconds: 5})
+	if err != nil || len(output.Messages) != 1 || time.Since(start) > 2*time.Second {
+		t.Errorf("ReceiveMessage() output = %+v, err = %v, elapsed = %s", output, err, time.Since(start))
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 200*time.Millisecond)
+	defer cancel()
+	_, err = f.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: &queueUrl, WaitTimeSeconds: 5})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReceiveMessage() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestFakeQueue(t *testing.T) {
+	f := NewFake()
+	ctx := context.TODO()
+	queueUrl := f.NewQueue("queue", nil)
+	f.NewQueue("other", nil)
+	getUrlOutput, err := f.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String("queue")})
+	if err != nil || aws.ToString(getUrlOutput.QueueUrl) != queueUrl {
+		t.Errorf("GetQueueUrl() output = %v, err = %v", getUrlOutput, err)
+	}
+	listOutput, err := f.ListQueues(ctx, &sqs.ListQueuesInput{MaxResults: aws.Int32(1)})
+	if err != nil || len(listOutput.QueueUrls) != 1 || listOutput.NextToken == nil {
+		t.Fatalf("ListQueues() output = %v, err = %v", listOutput, err)
+	}
+	listOutput, err = f.ListQueues(ctx, &sqs.ListQueuesInput{MaxResults: aws.Int32(1), NextToken: listOutput.NextToken})
+	if err != nil || len(listOutput.QueueUrls) != 1 || listOutput.QueueUrls[0] != queueUrl || listOutput.NextToken != nil {
+		t.Errorf("ListQueues() second page output = %v, err = %v", listOutput, err)
+	}
+	_, err = f.TagQueue(ctx, &sqs.TagQueueInput{QueueUrl: &queueUrl, Tags: map[string]string{"a": "1", "b": "2"}})
+	if err != nil {
+		t.Errorf("TagQueue() error = %v", err)
+	}
+	_, err = f.UntagQueue(ctx, &sqs.UntagQueueInput{QueueUrl: &queueUrl, TagKeys: []string{"a"}})
+	if err != nil {
+		t.Errorf("UntagQueue() error = %v", err)
+	}
+	tagsOutput, err := f.ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: &queueUrl})
+	if err != nil || len(tagsOutput.Tags) != 1 || tagsOutput.Tags["b"] != "2" {
+		t.Errorf("ListQueueTags() output = %v, err = %v", tagsOutput, err)
+	}
+	_, err = f.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl:   &queueUrl,
+		Attributes: map[string]string{"VisibilityTimeout": "60"},
+	})
+	if attributes := getAttributes(t, f, queueUrl); err != nil || attributes["VisibilityTimeout"] != "60" {
+		t.Errorf("SetQueueAttributes() attributes = %v, err = %v", attributes, err)
+	}
+	_, err = f.DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: &queueUrl})
+	if err != nil {
+		t.Errorf("DeleteQueue() error = %v", err)
+	}
+	_, err = f.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String("queue")})
+	var queueErr *types.QueueDoesNotExist
+	if !errors.As(err, &queueErr) {
+		t.Errorf("GetQueueUrl() error = %v, want *types.QueueDoesNotExist", err)
+	}
+}
+
+func TestFakeErrors(t *testing.T) {
+	for _, tt := range initListTestFakeError() {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFake()
+			err := tt.call(f, f.NewQueue("errors", nil))
+			var apiErr smithy.APIError
+			if !errors.As(err, &apiErr) || apiErr.ErrorCode() != tt.wantCode {
+				t.Errorf("error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func initFakeWithClock() (*Fake, *testClock) {
+	clock := &testClock{now: time.Now()}
+	return NewFake().SetClock(clock.Now), clock
+}
+
+func receive(t *testing.T, f *Fake, queueUrl string, maxNumberOfMessages int32) []types.Message {
+	output, err := f.ReceiveMessage(context.TODO(), &sqs.ReceiveMessageInput{
+		QueueUrl:              &queueUrl,
+		AttributeNames:        []types.QueueAttributeName{types.QueueAttributeNameAll},
+		MessageAttributeNames: []string{"All"},
+		MaxNumberOfMessages:   maxNumberOfMessages,
+	})
+	if err != nil {
+		t.Fatalf("ReceiveMessage() error = %v", err)
+	}
+	return output.Messages
+}
+
+func getAttributes(t *testing.T, f *Fake, queueUrl string) map[string]string {
+	output, err := f.GetQueueAttributes(context.TODO(), &sqs.GetQueueAttributesInput{
+		QueueUrl:       &queueUrl,
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameAll},
+	})
+	if err != nil {
+		t.Fatalf("GetQueueAttributes() error = %v", err)
+	}
+	return output.Attributes
+}
+
+func initListTestFakeError() []testFakeError {
+	return []testFakeError{
+		{
+			name: "queue does not exist",
+			call: func(f *Fake, _ string) error {
+				_, err := f.PurgeQueue(context.TODO(), &sqs.PurgeQueueInput{
+					QueueUrl: aws.String("https://google.com"),
+				})
+				return err
+			},
+			wantCode: "QueueDoesNotExist",
+		},
+		{
+			name: "invalid queue name",
+			call: func(f *Fake, _ string) error {
+				_, err := f.CreateQueue(context.TODO(), &sqs.CreateQueueInput{QueueName: aws.String("fifo.fifo")})
+				return err
+			},
+			wantCode: "InvalidParameterValue",
+		},
+		{
+			name: "queue name exists",
+			call: func(f *Fake, _ string) error {
+				_, err := f.CreateQueue(context.TODO(), &sqs.CreateQueueInput{
+					QueueName:  aws.String("errors"),
+					Attributes: map[string]string{"DelaySeconds": "10"},
+				})
+				return err
+			},
+			wantCode: "QueueNameExists",
+		},
+		{
+			name: "invalid attribute name",
+			call: func(f *Fake, queueUrl string) error {
+				_, err := f.SetQueueAttributes(context.TODO(), &sqs.SetQueueAttributesInput{
+					QueueUrl:   &queueUrl,
+					Attributes: map[string]string{"Unknown": "1"},
+				})
+				return err
+			},
+			wantCode: "InvalidAttributeName",
+		},
+		{
+			name: "invalid message attribute",
+			call: func(f *Fake, queueUrl string) error {
+				_, err := f.SendMessage(context.TODO(), &sqs.SendMessageInput{
+					QueueUrl:    &queueUrl,
+					MessageBody: aws.String("body test"),
+					MessageAttributes: map[string]types.MessageAttributeValue{
+						"number": {DataType: aws.String("Number"), StringValue: aws.String("abc")},
+					},
+				})
+				return err
+			},
+			wantCode: "InvalidParameterValue",
+		},
+		{
+			name: "empty batch",
+			call: func(f *Fake, queueUrl string) error {
+				_, err := f.DeleteMessageBatch(context.TODO(), &sqs.DeleteMessageBatchInput{QueueUrl: &queueUrl})
+				return err
+			},
+			wantCode: "EmptyBatchRequest",
+		},
+		{
+			name: "move task source is not a dead-letter queue",
+			call: func(f *Fake, queueUrl string) error {
+				_, err := f.StartMessageMoveTask(context.TODO(), &sqs.StartMessageMoveTaskInput{
+					SourceArn: aws.String(f.QueueArn(queueUrl)),
+				})
+				return err
+			},
+			wantCode: "InvalidParameterValue",
+		},
+	}
+}